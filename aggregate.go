@@ -0,0 +1,378 @@
+package sheetsql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// aggregateFunctions are the functions that fold a group of rows into a
+// single value.
+var aggregateFunctions = map[string]bool{
+	"COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true,
+}
+
+// aggregateExpr is an aggregate function call such as COUNT(*) or
+// SUM(DISTINCT Amount). Its value is computed once per group and looked up
+// from the evaluation environment.
+type aggregateExpr struct {
+	fn       string
+	arg      expr // nil for COUNT(*)
+	distinct bool
+}
+
+func (a *aggregateExpr) eval(env *evalEnv) (interface{}, error) {
+	value, ok := env.aggregates[a]
+	if !ok {
+		return nil, fmt.Errorf("aggregate function %s is not allowed here", a)
+	}
+	return value, nil
+}
+
+func (a *aggregateExpr) String() string {
+	switch {
+	case a.arg == nil:
+		return a.fn + "(*)"
+	case a.distinct:
+		return a.fn + "(DISTINCT " + a.arg.String() + ")"
+	}
+	return a.fn + "(" + a.arg.String() + ")"
+}
+
+// compute folds the rows of one group. Empty cells are ignored, except by
+// COUNT(*).
+func (a *aggregateExpr) compute(group []*evalEnv) (interface{}, error) {
+	acc := newAccumulator(a)
+	for _, env := range group {
+		if err := acc.add(env); err != nil {
+			return nil, err
+		}
+	}
+	return acc.result()
+}
+
+// accumulator folds rows into an aggregate one at a time, so that a running
+// total over a growing window frame need not start over for every row.
+type accumulator struct {
+	agg  *aggregateExpr
+	seen map[string]bool
+
+	count    int // rows for COUNT(*), otherwise values added
+	integers bool
+	intSum   int64
+	floatSum float64
+	best     interface{}
+	bestText string
+	err      error
+}
+
+func newAccumulator(a *aggregateExpr) *accumulator {
+	return &accumulator{agg: a, seen: make(map[string]bool), integers: true}
+}
+
+// add folds in the row of env. A value SUM or AVG cannot add makes result
+// fail rather than add.
+func (acc *accumulator) add(env *evalEnv) error {
+	a := acc.agg
+	if a.arg == nil {
+		acc.count++
+		return nil
+	}
+
+	value, err := a.arg.eval(env)
+	if err != nil {
+		return err
+	}
+	if isBlank(value) {
+		return nil
+	}
+
+	text := formatValue(value)
+	if a.distinct {
+		if acc.seen[text] {
+			return nil
+		}
+		acc.seen[text] = true
+	}
+	acc.count++
+
+	switch a.fn {
+	case "SUM", "AVG":
+		// Values are added as integers while they all are, and as floats
+		// from then on.
+		if acc.integers {
+			if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+				acc.intSum += n
+				acc.floatSum += float64(n)
+				return nil
+			}
+			acc.integers = false
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			if acc.err == nil {
+				acc.err = fmt.Errorf("%s: %q is not a number", a, text)
+			}
+			return nil
+		}
+		acc.floatSum += f
+	case "MIN", "MAX":
+		c := 0
+		if acc.best != nil {
+			c = compareCells(text, acc.bestText)
+		}
+		if acc.best == nil || a.fn == "MIN" && c < 0 || a.fn == "MAX" && c > 0 {
+			acc.best, acc.bestText = value, text
+		}
+	}
+	return nil
+}
+
+// result returns the aggregate of the rows added so far.
+func (acc *accumulator) result() (interface{}, error) {
+	switch acc.agg.fn {
+	case "COUNT":
+		return acc.count, nil
+	case "SUM", "AVG":
+		if acc.err != nil {
+			return nil, acc.err
+		}
+		if acc.count == 0 {
+			return nil, nil
+		}
+		if acc.agg.fn == "AVG" {
+			return acc.floatSum / float64(acc.count), nil
+		}
+		if acc.integers {
+			return acc.intSum, nil
+		}
+		return acc.floatSum, nil
+	case "MIN", "MAX":
+		return acc.best, nil
+	}
+	return nil, fmt.Errorf("unknown aggregate function %s", acc.agg.fn)
+}
+
+func toFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// parseAggregate builds an aggregate from the fluent API's spelling, such as
+// Aggregate("COUNT DISTINCT", "City", "Cities").
+func parseAggregate(fn, column string) (*aggregateExpr, error) {
+	words := strings.Fields(strings.ToUpper(fn))
+	if len(words) == 0 || !aggregateFunctions[words[0]] || len(words) > 2 || len(words) == 2 && words[1] != "DISTINCT" {
+		return nil, fmt.Errorf("unknown aggregate function %q", fn)
+	}
+
+	agg := &aggregateExpr{fn: words[0], distinct: len(words) == 2}
+	if column == "*" {
+		if agg.fn != "COUNT" || agg.distinct {
+			return nil, fmt.Errorf("%s(*) is not supported", fn)
+		}
+		return agg, nil
+	}
+
+	agg.arg = &columnRef{name: column}
+	return agg, nil
+}
+
+// grouped reports whether the query folds rows into groups: it has a GROUP
+// BY or HAVING clause or its select list uses an aggregate function.
+func (q *Query) grouped() bool {
+	if len(q.groupBy) > 0 || q.having != nil {
+		return true
+	}
+	for _, item := range q.columns {
+		if !item.star && containsAggregate(item.expr) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupRows returns one evaluation environment per result row. Ungrouped
+// queries get one per sheet row; grouped queries get one per group that
+// passes HAVING, with the group's aggregates computed.
+func (q *Query) groupRows(rows [][]interface{}, base *evalEnv) ([]*evalEnv, error) {
+	fieldMap := base.fieldMap
+
+	envs := make([]*evalEnv, len(rows))
+	for i, row := range rows {
+		envs[i] = base.with(row)
+	}
+
+	if !q.grouped() {
+		return envs, nil
+	}
+
+	having := q.resolveAliases(q.having, fieldMap)
+	aggregates, err := q.checkGrouping(having, fieldMap)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := q.partition(envs)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*evalEnv
+	for _, group := range groups {
+		env := base.with(nil)
+		env.aggregates = make(map[*aggregateExpr]interface{})
+		if len(group) > 0 {
+			env.row = group[0].row
+		}
+
+		for _, agg := range aggregates {
+			value, err := agg.compute(group)
+			if err != nil {
+				return nil, err
+			}
+			env.aggregates[agg] = value
+		}
+
+		if having != nil {
+			keep, err := evalCondition(having, env)
+			if err != nil {
+				return nil, err
+			}
+			if keep != true {
+				continue
+			}
+		}
+
+		result = append(result, env)
+	}
+
+	return result, nil
+}
+
+// partition splits envs by their GROUP BY values, keeping groups in the order
+// they first appear. Without GROUP BY every row is in one group, which exists
+// even when there are no rows so that COUNT(*) can report 0.
+func (q *Query) partition(envs []*evalEnv) ([][]*evalEnv, error) {
+	return partitionBy(envs, q.groupBy)
+}
+
+// partitionBy splits envs by the values of exprs, keeping partitions in the
+// order they first appear.
+func partitionBy(envs []*evalEnv, exprs []expr) ([][]*evalEnv, error) {
+	if len(exprs) == 0 {
+		return [][]*evalEnv{envs}, nil
+	}
+
+	var groups [][]*evalEnv
+	index := make(map[string]int)
+	for _, env := range envs {
+		parts := make([]string, len(exprs))
+		for i, e := range exprs {
+			value, err := e.eval(env)
+			if err != nil {
+				return nil, err
+			}
+			if isBlank(value) {
+				parts[i] = "\x00"
+			} else {
+				parts[i] = formatValue(value)
+			}
+		}
+
+		key := strings.Join(parts, "\x1f")
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], env)
+	}
+
+	return groups, nil
+}
+
+// checkGrouping checks that outside aggregate functions the select list,
+// HAVING and ORDER BY only use GROUP BY expressions, and returns every
+// aggregate they contain.
+func (q *Query) checkGrouping(having expr, fieldMap map[string]int) ([]*aggregateExpr, error) {
+	for _, e := range q.groupBy {
+		if err := checkColumns(e, fieldMap, "GROUP BY"); err != nil {
+			return nil, err
+		}
+		if containsAggregate(e) {
+			return nil, fmt.Errorf("aggregate functions are not allowed in GROUP BY")
+		}
+	}
+	if err := checkColumns(having, fieldMap, "HAVING"); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool)
+	for _, e := range q.groupBy {
+		keys[e.String()] = true
+	}
+
+	if len(q.columns) == 0 {
+		return nil, fmt.Errorf("SELECT * cannot be used with GROUP BY or aggregate functions")
+	}
+
+	exprs := []expr{having}
+	for _, item := range q.columns {
+		if item.star {
+			return nil, fmt.Errorf("SELECT * cannot be used with GROUP BY or aggregate functions")
+		}
+		exprs = append(exprs, item.expr)
+	}
+	for _, item := range q.orderBy {
+		e := q.resolveAliases(item.expr, fieldMap)
+		if err := checkColumns(e, fieldMap, "ORDER BY"); err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+	}
+
+	var aggregates []*aggregateExpr
+	var err error
+	for _, e := range exprs {
+		walkExpr(e, func(e expr) bool {
+			if err != nil || keys[e.String()] {
+				return false
+			}
+
+			switch e := e.(type) {
+			case *aggregateExpr:
+				if containsAggregate(e.arg) {
+					err = fmt.Errorf("aggregate functions cannot be nested: %s", e)
+				}
+				aggregates = append(aggregates, e)
+				return false
+			case *columnRef:
+				err = fmt.Errorf("column %q must appear in GROUP BY or be used in an aggregate function", e.key())
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return aggregates, nil
+}
+
+func containsAggregate(e expr) bool {
+	found := false
+	walkExpr(e, func(e expr) bool {
+		if _, ok := e.(*aggregateExpr); ok {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
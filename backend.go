@@ -0,0 +1,103 @@
+package sheetsql
+
+import (
+	"google.golang.org/api/sheets/v4"
+)
+
+// Backend is the storage layer a Client reads from and writes to. Ranges use
+// the A1 notation of the Sheets API (e.g. "Users!A:Z" or "Users!A2:Z2").
+type Backend interface {
+	// ReadRange returns the values in readRange, one slice per row.
+	ReadRange(spreadsheetID, readRange string) ([][]interface{}, error)
+
+	// ReadRanges returns the values of several ranges in one request, in the
+	// order the ranges were given.
+	ReadRanges(spreadsheetID string, readRanges []string) ([][][]interface{}, error)
+
+	// AppendRows appends rows after the last row of the table in writeRange.
+	AppendRows(spreadsheetID, writeRange string, rows [][]interface{}) error
+
+	// UpdateRange overwrites the cells in updateRange with rows.
+	UpdateRange(spreadsheetID, updateRange string, rows [][]interface{}) error
+
+	// BatchUpdate applies structural changes such as row deletion.
+	BatchUpdate(spreadsheetID string, req *sheets.BatchUpdateSpreadsheetRequest) error
+
+	// SheetProperties returns the metadata of every sheet in the spreadsheet.
+	SheetProperties(spreadsheetID string) ([]*sheets.SheetProperties, error)
+}
+
+type sheetsBackend struct {
+	service *sheets.Service
+}
+
+// NewSheetsBackend returns a Backend that talks to the Google Sheets API.
+func NewSheetsBackend(service *sheets.Service) Backend {
+	return &sheetsBackend{service: service}
+}
+
+func (b *sheetsBackend) ReadRange(spreadsheetID, readRange string) ([][]interface{}, error) {
+	resp, err := b.service.Spreadsheets.Values.Get(spreadsheetID, readRange).Do()
+	if err != nil {
+		return nil, err
+	}
+	return resp.Values, nil
+}
+
+func (b *sheetsBackend) ReadRanges(spreadsheetID string, readRanges []string) ([][][]interface{}, error) {
+	resp, err := b.service.Spreadsheets.Values.BatchGet(spreadsheetID).Ranges(readRanges...).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([][][]interface{}, len(readRanges))
+	for i, valueRange := range resp.ValueRanges {
+		if i < len(values) {
+			values[i] = valueRange.Values
+		}
+	}
+	return values, nil
+}
+
+func (b *sheetsBackend) AppendRows(spreadsheetID, writeRange string, rows [][]interface{}) error {
+	valueRange := &sheets.ValueRange{
+		Values: rows,
+	}
+
+	_, err := b.service.Spreadsheets.Values.Append(spreadsheetID, writeRange, valueRange).
+		ValueInputOption("RAW").
+		InsertDataOption("INSERT_ROWS").
+		Do()
+	return err
+}
+
+func (b *sheetsBackend) UpdateRange(spreadsheetID, updateRange string, rows [][]interface{}) error {
+	valueRange := &sheets.ValueRange{
+		Values: rows,
+	}
+
+	_, err := b.service.Spreadsheets.Values.Update(spreadsheetID, updateRange, valueRange).
+		ValueInputOption("RAW").
+		Do()
+	return err
+}
+
+func (b *sheetsBackend) BatchUpdate(spreadsheetID string, req *sheets.BatchUpdateSpreadsheetRequest) error {
+	_, err := b.service.Spreadsheets.BatchUpdate(spreadsheetID, req).Do()
+	return err
+}
+
+func (b *sheetsBackend) SheetProperties(spreadsheetID string) ([]*sheets.SheetProperties, error) {
+	resp, err := b.service.Spreadsheets.Get(spreadsheetID).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	props := make([]*sheets.SheetProperties, 0, len(resp.Sheets))
+	for _, sheet := range resp.Sheets {
+		if sheet.Properties != nil {
+			props = append(props, sheet.Properties)
+		}
+	}
+	return props, nil
+}
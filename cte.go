@@ -0,0 +1,265 @@
+package sheetsql
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// maxRecursion is how many times the recursive part of a common table
+// expression may run before the query fails, in case it never stops
+// producing rows.
+const maxRecursion = 1000
+
+// cte is a common table expression: a query named in a WITH clause that the
+// rest of the statement reads like a sheet. Its rows are computed once per
+// execution. A recursive one runs recursive, the UNION [ALL] SELECTs that
+// read it, on the rows the previous round added until no new rows come back.
+type cte struct {
+	name      string
+	columns   []string
+	query     *Query
+	recursive []setOp
+}
+
+func (c *cte) String() string {
+	str := quoteIdentifier(c.name)
+	if c.columns != nil {
+		quoted := make([]string, len(c.columns))
+		for i, column := range c.columns {
+			quoted[i] = quoteIdentifier(column)
+		}
+		str += " (" + strings.Join(quoted, ", ") + ")"
+	}
+
+	str += " AS (" + c.query.String()
+	for _, op := range c.recursive {
+		str += " " + op.String()
+	}
+	return str + ")"
+}
+
+// withString returns the WITH clause defining ctes.
+func withString(ctes []*cte) string {
+	str := "WITH "
+	for _, c := range ctes {
+		if c.recursive != nil {
+			str = "WITH RECURSIVE "
+			break
+		}
+	}
+
+	for i, c := range ctes {
+		if i > 0 {
+			str += ", "
+		}
+		str += c.String()
+	}
+	return str
+}
+
+// parseWith reads a WITH clause and brings its common table expressions into
+// scope. The caller restores the previous scope once the statement ends.
+func (p *parser) parseWith() ([]*cte, error) {
+	if err := p.expectKeyword("WITH"); err != nil {
+		return nil, err
+	}
+	recursive := p.acceptKeyword("RECURSIVE")
+
+	p.ctes = maps.Clone(p.ctes)
+	if p.ctes == nil {
+		p.ctes = make(map[string]*cte)
+	}
+
+	var ctes []*cte
+	for {
+		tok := p.peek()
+		name, err := p.parseIdentifier("name")
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range ctes {
+			if c.name == name {
+				return nil, p.errorf(tok, "WITH query %s specified more than once", quoteIdentifier(name))
+			}
+		}
+
+		c := &cte{name: name}
+		if p.isOperator("(") {
+			if c.columns, err = p.parseColumnList(); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expectKeyword("AS"); err != nil {
+			return nil, err
+		}
+
+		// Only a recursive WITH query can read itself.
+		if recursive {
+			p.ctes[name] = c
+		}
+		if c.query, err = p.parseSubquery(); err != nil {
+			return nil, err
+		}
+		if recursive {
+			if err := c.splitRecursive(); err != nil {
+				return nil, p.errorf(tok, "%v", err)
+			}
+		}
+		p.ctes[name] = c
+
+		ctes = append(ctes, c)
+		if !p.acceptOperator(",") {
+			return ctes, nil
+		}
+	}
+}
+
+// splitRecursive moves the set operations of c's query that read c to
+// c.recursive, checking that c has the form "SELECT ... UNION [ALL] SELECT
+// ..." with only the trailing SELECTs reading c.
+func (c *cte) splitRecursive() error {
+	q := c.query
+	first := -1
+	for i, op := range q.setOps {
+		if op.query.refersTo(c) && first < 0 {
+			first = i
+		}
+	}
+	if first < 0 && !q.refersTo(c) {
+		return nil
+	}
+
+	name := quoteIdentifier(c.name)
+	switch {
+	case q.coreRefersTo(c):
+		return fmt.Errorf("recursive query %s must start with a SELECT that does not read it", name)
+	case len(q.orderBy) > 0 || q.limit > 0 || q.offset > 0:
+		return fmt.Errorf("recursive query %s cannot have ORDER BY, LIMIT or OFFSET", name)
+	}
+	for _, op := range q.setOps[first:] {
+		if op.op != "UNION" || !op.query.refersTo(c) {
+			return fmt.Errorf("recursive query %s must end with the SELECTs that read it, joined by UNION [ALL]", name)
+		}
+	}
+
+	c.recursive = q.setOps[first:]
+	q.setOps = q.setOps[:first:first]
+	return nil
+}
+
+// refersTo reports whether q or a query combined with it reads c.
+func (q *Query) refersTo(c *cte) bool {
+	if q.coreRefersTo(c) {
+		return true
+	}
+	for _, op := range q.setOps {
+		if op.query.refersTo(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// coreRefersTo reports whether q reads c from a table or a subquery, leaving
+// out the queries combined with q.
+func (q *Query) coreRefersTo(c *cte) bool {
+	for _, t := range q.tableCTEs() {
+		if t == c {
+			return true
+		}
+	}
+	for _, sub := range q.subqueries() {
+		if sub.refersTo(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// cteValues returns the header and rows of c, computing them the first time
+// c is read in x.
+func (x *execution) cteValues(c *cte) ([][]interface{}, error) {
+	if values, ok := x.ctes[c]; ok {
+		return values, nil
+	}
+
+	columns, rows, err := c.query.run(x, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.columns != nil {
+		if columns != nil && len(columns) != len(c.columns) {
+			return nil, fmt.Errorf("WITH query %s has %d columns but its SELECT returns %d", quoteIdentifier(c.name), len(c.columns), len(columns))
+		}
+		columns = c.columns
+	}
+	if columns == nil {
+		x.ctes[c] = nil
+		return nil, nil
+	}
+
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column
+	}
+
+	if c.recursive != nil {
+		if rows, err = x.recurse(c, header, rows); err != nil {
+			return nil, err
+		}
+	}
+
+	values := append([][]interface{}{header}, rows...)
+	x.ctes[c] = values
+	return values, nil
+}
+
+// recurse runs the recursive part of c until it adds no rows, starting from
+// rows, and returns every row found. While it runs, c reads as the rows the
+// previous round added.
+func (x *execution) recurse(c *cte, header []interface{}, rows [][]interface{}) ([][]interface{}, error) {
+	// add appends to dst the rows not found before, or all of them for
+	// UNION ALL.
+	seen := make(map[string]bool)
+	add := func(dst, rows [][]interface{}, all bool) [][]interface{} {
+		for _, row := range rows {
+			key := rowKey(row)
+			if all || !seen[key] {
+				dst = append(dst, row)
+			}
+			seen[key] = true
+		}
+		return dst
+	}
+
+	rows = add(nil, rows, c.recursive[0].all)
+	found := rows
+	for round := 0; len(rows) > 0; round++ {
+		if round == maxRecursion {
+			return nil, fmt.Errorf("recursive query %s did not finish after %d rounds", quoteIdentifier(c.name), maxRecursion)
+		}
+
+		x.ctes[c] = append([][]interface{}{header}, rows...)
+		// Subqueries reading c must see the new rows.
+		clear(x.results)
+
+		var added [][]interface{}
+		for _, op := range c.recursive {
+			columns, opRows, err := op.query.run(x, nil)
+			if err != nil {
+				return nil, err
+			}
+			if columns != nil && len(columns) != len(header) {
+				return nil, fmt.Errorf("queries combined with UNION have %d and %d columns", len(header), len(columns))
+			}
+			added = add(added, opRows, op.all)
+		}
+
+		found = append(found, added...)
+		rows = added
+	}
+
+	delete(x.ctes, c)
+	return found, nil
+}
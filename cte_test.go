@@ -0,0 +1,120 @@
+package sheetsql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuery_CommonTableExpressions(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected [][]interface{}
+		wantErr  string
+	}{
+		{
+			name:     "simple",
+			sql:      "WITH big AS (SELECT * FROM Orders WHERE Total > 5) SELECT ID FROM big",
+			expected: [][]interface{}{{"101"}, {"102"}},
+		},
+		{
+			name:     "joined with a sheet",
+			sql:      "WITH spend AS (SELECT UserID, SUM(Total) AS Spent FROM Orders GROUP BY UserID) SELECT Name, Spent FROM Users JOIN spend ON spend.UserID = Users.ID",
+			expected: [][]interface{}{{"Ann", int64(14)}, {"Ben", int64(7)}},
+		},
+		{
+			name:     "column names and chaining",
+			sql:      "WITH a (Who) AS (SELECT Name FROM Users), b AS (SELECT Who FROM a WHERE Who != 'Ben') SELECT * FROM b",
+			expected: [][]interface{}{{"Ann"}, {"Cat"}},
+		},
+		{
+			name:     "read twice",
+			sql:      "WITH t AS (SELECT UserID FROM Orders WHERE Total > 6) SELECT ID FROM Users WHERE ID IN (SELECT UserID FROM t) AND EXISTS (SELECT * FROM t)",
+			expected: [][]interface{}{{"1"}, {"2"}},
+		},
+		{
+			name:     "shadowing the sheet it reads",
+			sql:      "WITH Users AS (SELECT * FROM Users WHERE ID = '1') SELECT Name FROM Users",
+			expected: [][]interface{}{{"Ann"}},
+		},
+		{
+			name:     "in a subquery",
+			sql:      "SELECT Name FROM Users WHERE ID IN (WITH t AS (SELECT UserID FROM Orders) SELECT UserID FROM t)",
+			expected: [][]interface{}{{"Ann"}, {"Ben"}},
+		},
+		{
+			name: "recursive hierarchy",
+			sql: "WITH RECURSIVE chain (ID, Name, Depth) AS (SELECT ID, Name, 0 FROM Org WHERE ParentID IS NULL " +
+				"UNION ALL SELECT Org.ID, Org.Name, Depth + 1 FROM Org JOIN chain ON Org.ParentID = chain.ID) " +
+				"SELECT Name, Depth FROM chain ORDER BY Depth, Name",
+			expected: [][]interface{}{{"CEO", 0}, {"CFO", int64(1)}, {"CTO", int64(1)}, {"Dev", int64(2)}, {"Intern", int64(3)}},
+		},
+		{
+			name: "recursive subquery",
+			sql: "WITH RECURSIVE team AS (SELECT ID FROM Org WHERE Name = 'CTO' " +
+				"UNION SELECT ID FROM Org WHERE ParentID IN (SELECT ID FROM team)) " +
+				"SELECT Name FROM Org WHERE ID IN (SELECT ID FROM team)",
+			expected: [][]interface{}{{"CTO"}, {"Dev"}, {"Intern"}},
+		},
+		{
+			name:     "recursive union stops at cycles",
+			sql:      "WITH RECURSIVE reach (Node) AS (SELECT Src FROM Graph WHERE Src = 'a' UNION SELECT Dst FROM Graph JOIN reach ON Src = Node) SELECT * FROM reach",
+			expected: [][]interface{}{{"a"}, {"b"}, {"c"}},
+		},
+		{
+			name:    "recursive union all does not",
+			sql:     "WITH RECURSIVE reach (Node) AS (SELECT Src FROM Graph WHERE Src = 'a' UNION ALL SELECT Dst FROM Graph JOIN reach ON Src = Node) SELECT * FROM reach",
+			wantErr: "recursive query reach did not finish after 1000 rounds",
+		},
+		{
+			name:    "column count",
+			sql:     "WITH t (A, B) AS (SELECT ID FROM Users) SELECT * FROM t",
+			wantErr: "WITH query t has 2 columns but its SELECT returns 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newJoinTestBackend()
+			backend.SetSheet("Org", [][]interface{}{
+				{"ID", "Name", "ParentID"},
+				{"1", "CEO"},
+				{"2", "CTO", "1"},
+				{"3", "Dev", "2"},
+				{"4", "CFO", "1"},
+				{"5", "Intern", "3"},
+			})
+			backend.SetSheet("Graph", [][]interface{}{
+				{"Src", "Dst"},
+				{"a", "b"},
+				{"b", "c"},
+				{"c", "a"},
+			})
+
+			query, err := NewSQLParser(NewClientWithBackend("test-id", backend)).parseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("parseSQL() error = %v", err)
+			}
+			if got := query.String(); got != tt.sql {
+				t.Errorf("String() = %q, expected %q", got, tt.sql)
+			}
+
+			_, rows, err := query.rows()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("rows() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rows() error = %v", err)
+			}
+			if !reflect.DeepEqual(rows, tt.expected) {
+				t.Errorf("rows = %v, expected %v", rows, tt.expected)
+			}
+			if backend.reads != 1 {
+				t.Errorf("query made %d read requests, expected 1", backend.reads)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package sheetsql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Value is a value passed to or returned by a custom function or operator:
+// the text of a cell, a number, string or bool from the SQL, a time.Time,
+// whatever value was given to Where, or nil for NULL.
+type Value = interface{}
+
+// Function is a scalar function added with RegisterFunction.
+type Function func(args ...Value) (Value, error)
+
+// Operator is a binary operator added with RegisterOperator. It reports
+// whether left, usually a cell, stands in the relation to right.
+type Operator func(left, right Value) (bool, error)
+
+// RegisterFunction makes fn callable by name, ignoring case, in the SQL run
+// by p:
+//
+//	parser.RegisterFunction("SLUGIFY", func(args ...sheetsql.Value) (sheetsql.Value, error) {
+//		return slug.Make(fmt.Sprint(args[0])), nil
+//	})
+//	err := parser.Query("SELECT SLUGIFY(Title) AS Slug FROM Posts", &posts)
+//
+// fn is called once per row with however many arguments the SQL passes, so
+// it should check their number. Arguments are nil for NULL. An error fails
+// the query. RegisterFunction panics if name is not a plain identifier, is a
+// reserved word or names a built-in function. Register functions before p is
+// used from several goroutines.
+func (p *SQLParser) RegisterFunction(name string, fn Function) {
+	upper := strings.ToUpper(name)
+	switch {
+	case quoteIdentifier(name) != name:
+		panic(fmt.Sprintf("sheetsql: invalid function name %q", name))
+	case scalarFunctions[upper] != nil || aggregateFunctions[upper] || windowFunctions[upper] != nil || upper == "CAST" || upper == "EXTRACT":
+		panic(fmt.Sprintf("sheetsql: %s is a built-in function", upper))
+	case fn == nil:
+		panic("sheetsql: RegisterFunction with nil function")
+	}
+
+	if p.functions == nil {
+		p.functions = make(map[string]*scalarFunction)
+	}
+	p.cache.clear()
+	p.functions[upper] = &scalarFunction{
+		minArgs:  0,
+		maxArgs:  -1,
+		nullArgs: true,
+		call: func(env *evalEnv, args []interface{}) (interface{}, error) {
+			return fn(args...)
+		},
+	}
+}
+
+// RegisterOperator adds a binary operator that can be used, ignoring case and
+// optionally preceded by NOT, in Where, OrWhere and Having and in SQL run
+// against c:
+//
+//	client.RegisterOperator("NEAR", func(left, right sheetsql.Value) (bool, error) {
+//		return distance(left, right) < 10, nil
+//	})
+//	client.From("Stores").Where("Location", "near", home)
+//	parser.Query("SELECT * FROM Stores WHERE Location NEAR '52.5,13.4'", &stores)
+//
+// In SQL, op is not called when either value is NULL, and the condition is
+// unknown. An error fails the query. name becomes a keyword in SQL, so a
+// column of the same name must be quoted when it follows another value.
+// RegisterOperator panics if name is not a plain identifier or is a reserved
+// word. Register operators before c is used from several goroutines.
+func (c *Client) RegisterOperator(name string, op Operator) {
+	switch {
+	case quoteIdentifier(name) != name:
+		panic(fmt.Sprintf("sheetsql: invalid operator name %q", name))
+	case op == nil:
+		panic("sheetsql: RegisterOperator with nil operator")
+	}
+
+	if c.operators == nil {
+		c.operators = make(map[string]Operator)
+	}
+	c.operators[strings.ToUpper(name)] = op
+	c.operatorsVersion++
+}
+
+// version returns the operatorsVersion of c, which may be nil.
+func (c *Client) version() int {
+	if c == nil {
+		return 0
+	}
+	return c.operatorsVersion
+}
+
+// customOperator returns the registered operator named op, negated if op is
+// "NOT name", or nil if there is none.
+func (c *Client) customOperator(op string) Operator {
+	if c == nil {
+		return nil
+	}
+	if fn := c.operators[op]; fn != nil {
+		return fn
+	}
+
+	name, negated := strings.CutPrefix(op, "NOT ")
+	fn := c.operators[name]
+	if !negated || fn == nil {
+		return nil
+	}
+	return func(left, right Value) (bool, error) {
+		result, err := fn(left, right)
+		return !result, err
+	}
+}
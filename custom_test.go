@@ -0,0 +1,210 @@
+package sheetsql
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSQLParser_RegisterFunction(t *testing.T) {
+	parser := NewSQLParser(SetupTestData().Client("test-id"))
+	parser.RegisterFunction("slugify", func(args ...Value) (Value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("takes 1 argument, not %d", len(args))
+		}
+		if args[0] == nil {
+			return "none", nil
+		}
+		return strings.ReplaceAll(strings.ToLower(fmt.Sprint(args[0])), " ", "-"), nil
+	})
+	parser.RegisterFunction("DOUBLE", func(args ...Value) (Value, error) {
+		n, err := strconv.Atoi(fmt.Sprint(args[0]))
+		return n * 2, err
+	})
+
+	tests := []struct {
+		name     string
+		sql      string
+		expected [][]interface{}
+		wantErr  string
+	}{
+		{
+			name:     "select list",
+			sql:      "SELECT SLUGIFY(Name), Slugify(NULL), DOUBLE(Age) + 1 FROM Users WHERE ID = 1",
+			expected: [][]interface{}{{"john-doe", "none", int64(61)}},
+		},
+		{
+			name:     "where and order by",
+			sql:      "SELECT ID FROM Users WHERE slugify(City) = 'new-york' ORDER BY DOUBLE(Age) DESC",
+			expected: [][]interface{}{{1}, {4}},
+		},
+		{
+			name:     "inside built-in functions",
+			sql:      "SELECT UPPER(SLUGIFY(City)) FROM Users WHERE ID = 2",
+			expected: [][]interface{}{{"LOS-ANGELES"}},
+		},
+		{
+			name:    "error",
+			sql:     "SELECT SLUGIFY(Name, City) FROM Users",
+			wantErr: "SLUGIFY: takes 1 argument, not 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := parser.parseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("parseSQL() error = %v", err)
+			}
+
+			_, rows, err := query.rows()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("rows() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rows() error = %v", err)
+			}
+			if !reflect.DeepEqual(rows, tt.expected) {
+				t.Errorf("rows = %#v, expected %#v", rows, tt.expected)
+			}
+		})
+	}
+
+	_, err := NewSQLParser(parser.client).parse("SELECT SLUGIFY(Name) FROM Users")
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Msg != "unknown function SLUGIFY" {
+		t.Errorf("parse() error = %v, expected unknown function on another parser", err)
+	}
+}
+
+func TestClient_RegisterOperator(t *testing.T) {
+	client := SetupTestData().Client("test-id")
+	client.RegisterOperator("near", func(left, right Value) (bool, error) {
+		a, err := strconv.ParseFloat(fmt.Sprint(left), 64)
+		if err != nil {
+			return false, err
+		}
+		b, err := strconv.ParseFloat(fmt.Sprint(right), 64)
+		if err != nil {
+			return false, err
+		}
+		return math.Abs(a-b) <= 3, nil
+	})
+	parser := NewSQLParser(client)
+
+	ids := func(users []User) []int {
+		var ids []int
+		for _, user := range users {
+			ids = append(ids, user.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name     string
+		query    func() *Query
+		expected []int
+		wantErr  string
+	}{
+		{
+			name:     "where",
+			query:    func() *Query { return client.From("Users").Where("Age", "NEAR", 27) },
+			expected: []int{1, 2, 4},
+		},
+		{
+			name:     "not",
+			query:    func() *Query { return client.From("Users").Where("Age", "not near", 26).Where("Age", ">", 25) },
+			expected: []int{1, 3},
+		},
+		{
+			name: "sql",
+			query: func() *Query {
+				q, _ := parser.parseSQL("SELECT * FROM Users WHERE Age Near 33 OR ID NOT NEAR 1")
+				return q
+			},
+			expected: []int{1, 3, 5},
+		},
+		{
+			name:    "error",
+			query:   func() *Query { return client.From("Users").Where("Name", "NEAR", 30) },
+			wantErr: `strconv.ParseFloat: parsing "John Doe": invalid syntax`,
+		},
+		{
+			name:    "other client",
+			query:   func() *Query { return SetupTestData().Client("test-id").From("Users").Where("Age", "NEAR", 27) },
+			wantErr: `unknown operator "NEAR"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var users []User
+			err := tt.query().Get(&users)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Get() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got := ids(users); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Get() IDs = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+
+	query, err := parser.parseSQL("SELECT City FROM Users GROUP BY City HAVING COUNT(*) NEAR 4")
+	if err != nil {
+		t.Fatalf("parseSQL() error = %v", err)
+	}
+	if got := query.String(); got != "SELECT City FROM Users GROUP BY City HAVING COUNT(*) NEAR 4" {
+		t.Errorf("String() = %q", got)
+	}
+	_, rows, err := query.rows()
+	if err != nil {
+		t.Fatalf("rows() error = %v", err)
+	}
+	if len(rows) != 4 {
+		t.Errorf("rows() = %v, expected every city", rows)
+	}
+}
+
+func TestRegister_InvalidNames(t *testing.T) {
+	noop := func(args ...Value) (Value, error) { return nil, nil }
+	near := func(left, right Value) (bool, error) { return false, nil }
+
+	tests := []struct {
+		name     string
+		register func()
+		expected string
+	}{
+		{"function with spaces", func() { NewSQLParser(nil).RegisterFunction("MY FN", noop) }, `sheetsql: invalid function name "MY FN"`},
+		{"reserved function", func() { NewSQLParser(nil).RegisterFunction("select", noop) }, `sheetsql: invalid function name "select"`},
+		{"built-in function", func() { NewSQLParser(nil).RegisterFunction("Upper", noop) }, "sheetsql: UPPER is a built-in function"},
+		{"aggregate", func() { NewSQLParser(nil).RegisterFunction("SUM", noop) }, "sheetsql: SUM is a built-in function"},
+		{"nil function", func() { NewSQLParser(nil).RegisterFunction("F", nil) }, "sheetsql: RegisterFunction with nil function"},
+		{"symbol operator", func() { (&Client{}).RegisterOperator("~=", near) }, `sheetsql: invalid operator name "~="`},
+		{"reserved operator", func() { (&Client{}).RegisterOperator("like", near) }, `sheetsql: invalid operator name "like"`},
+		{"nil operator", func() { (&Client{}).RegisterOperator("NEAR", nil) }, "sheetsql: RegisterOperator with nil operator"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != tt.expected {
+					t.Errorf("panic = %v, expected %q", r, tt.expected)
+				}
+			}()
+			tt.register()
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package sheetsql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout      = "2006-01-02"
+	timestampLayout = "2006-01-02 15:04:05"
+)
+
+// isoLayouts are always accepted when reading a date, so that values this
+// package formats can be read back whatever layouts the client uses.
+var isoLayouts = []string{dateLayout, timestampLayout, "2006-01-02T15:04:05", time.RFC3339}
+
+// defaultDateLayouts are the layouts tried after ISO 8601 unless the client
+// sets its own.
+var defaultDateLayouts = []string{"1/2/2006", "1/2/2006 15:04:05", "1/2/2006 15:04"}
+
+// serialEpoch is day zero of the serial numbers Sheets uses for dates.
+var serialEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// SetDateLayouts sets the time.Parse layouts used, after ISO 8601, to read
+// dates from cells, replacing the default month/day/year layouts. For
+// day/month/year sheets:
+//
+//	client.SetDateLayouts("2/1/2006", "2/1/2006 15:04")
+func (c *Client) SetDateLayouts(layouts ...string) {
+	c.dateLayouts = layouts
+}
+
+// now returns the current time, from the client's clock if it has one.
+func (c *Client) now() time.Time {
+	if c == nil || c.clock == nil {
+		return time.Now()
+	}
+	return c.clock()
+}
+
+// isDate reports whether t has no time of day.
+func isDate(t time.Time) bool {
+	return t.Equal(t.Truncate(24 * time.Hour))
+}
+
+// toTime converts a value to a time. Strings are parsed with the client's
+// date layouts, and numbers, or strings holding them, are read as Sheets
+// serial dates: days since 30 December 1899, with the time of day as the
+// fraction. Times carry no time zone and are kept in UTC.
+func (q *Query) toTime(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case int:
+		return serialTime(float64(v)), nil
+	case int64:
+		return serialTime(float64(v)), nil
+	case float64:
+		return serialTime(v), nil
+	}
+
+	s := strings.TrimSpace(formatValue(v))
+	layouts := defaultDateLayouts
+	if q.client != nil && q.client.dateLayouts != nil {
+		layouts = q.client.dateLayouts
+	}
+	for _, layouts := range [][]string{isoLayouts, layouts} {
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t.UTC(), nil
+			}
+		}
+	}
+
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return serialTime(f), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date", s)
+}
+
+func serialTime(days float64) time.Time {
+	return serialEpoch.Add(time.Duration(days * float64(24*time.Hour))).Round(time.Second)
+}
+
+// compareTemporal applies a comparison operator chronologically when either
+// value is a time, converting the other one with toTime. ok is false when
+// neither value is a time or op is not a comparison; the result is nil when
+// the other value is an empty cell or cannot be read as a date, so that a
+// stray "N/A" in a date column matches nothing instead of failing the query.
+func (q *Query) compareTemporal(left interface{}, op string, right interface{}) (result interface{}, ok bool, err error) {
+	_, leftTime := left.(time.Time)
+	_, rightTime := right.(time.Time)
+	if !leftTime && !rightTime {
+		return nil, false, nil
+	}
+
+	switch op {
+	case "=", "==", "!=", "<>", "<", ">", "<=", ">=":
+	default:
+		return nil, false, nil
+	}
+
+	if isBlank(left) || isBlank(right) {
+		return nil, true, nil
+	}
+
+	a, err := q.toTime(left)
+	if err != nil {
+		return nil, true, nil
+	}
+	b, err := q.toTime(right)
+	if err != nil {
+		return nil, true, nil
+	}
+
+	switch op {
+	case "=", "==":
+		return a.Equal(b), true, nil
+	case "!=", "<>":
+		return !a.Equal(b), true, nil
+	case "<":
+		return a.Before(b), true, nil
+	case ">":
+		return a.After(b), true, nil
+	case "<=":
+		return !a.After(b), true, nil
+	}
+	return !a.Before(b), true, nil
+}
+
+// dateUnits are the units DATE_TRUNC, DATE_ADD and EXTRACT accept.
+var dateUnits = map[string]bool{
+	"YEAR": true, "QUARTER": true, "MONTH": true, "WEEK": true, "DAY": true,
+	"HOUR": true, "MINUTE": true, "SECOND": true,
+}
+
+// extractUnits are the parts EXTRACT can return besides the dateUnits.
+var extractUnits = map[string]bool{"DOW": true, "DOY": true, "EPOCH": true}
+
+func dateUnit(v interface{}) (string, error) {
+	unit := strings.ToUpper(strings.TrimSpace(formatValue(v)))
+	if !dateUnits[unit] {
+		return "", fmt.Errorf("unknown unit %q", formatValue(v))
+	}
+	return unit, nil
+}
+
+func nowFunction(env *evalEnv, args []interface{}) (interface{}, error) {
+	return env.query.client.now().UTC().Truncate(time.Second), nil
+}
+
+// dateFunction converts a value to a date, dropping any time of day.
+func dateFunction(env *evalEnv, args []interface{}) (interface{}, error) {
+	if isBlank(args[0]) {
+		return nil, nil
+	}
+	t, err := env.query.toTime(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return t.Truncate(24 * time.Hour), nil
+}
+
+// dateTrunc implements DATE_TRUNC(unit, x), which rounds x down to the start
+// of its year, quarter, month, week (starting on Monday), day, hour, minute
+// or second.
+func dateTrunc(env *evalEnv, args []interface{}) (interface{}, error) {
+	unit, err := dateUnit(args[0])
+	if err != nil || isBlank(args[1]) {
+		return nil, err
+	}
+	t, err := env.query.toTime(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	year, month, day := t.Date()
+	switch unit {
+	case "YEAR":
+		return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC), nil
+	case "QUARTER":
+		return time.Date(year, (month-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC), nil
+	case "MONTH":
+		return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), nil
+	case "WEEK":
+		return time.Date(year, month, day-(int(t.Weekday())+6)%7, 0, 0, 0, 0, time.UTC), nil
+	case "DAY":
+		return t.Truncate(24 * time.Hour), nil
+	case "HOUR":
+		return t.Truncate(time.Hour), nil
+	case "MINUTE":
+		return t.Truncate(time.Minute), nil
+	}
+	return t.Truncate(time.Second), nil
+}
+
+// dateAdd implements DATE_ADD(x, n, unit). Adding months or years to the end
+// of a month overflows into the next month, as with time.AddDate.
+func dateAdd(env *evalEnv, args []interface{}) (interface{}, error) {
+	if isBlank(args[0]) {
+		return nil, nil
+	}
+	t, err := env.query.toTime(args[0])
+	if err != nil {
+		return nil, err
+	}
+	n, err := intArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	unit, err := dateUnit(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	switch unit {
+	case "YEAR":
+		return t.AddDate(int(n), 0, 0), nil
+	case "QUARTER":
+		return t.AddDate(0, 3*int(n), 0), nil
+	case "MONTH":
+		return t.AddDate(0, int(n), 0), nil
+	case "WEEK":
+		return t.AddDate(0, 0, 7*int(n)), nil
+	case "DAY":
+		return t.AddDate(0, 0, int(n)), nil
+	case "HOUR":
+		return t.Add(time.Duration(n) * time.Hour), nil
+	case "MINUTE":
+		return t.Add(time.Duration(n) * time.Minute), nil
+	}
+	return t.Add(time.Duration(n) * time.Second), nil
+}
+
+// extractExpr is EXTRACT(unit FROM x), which returns one part of a date as
+// an integer.
+type extractExpr struct {
+	unit    string
+	operand expr
+}
+
+func (e *extractExpr) eval(env *evalEnv) (interface{}, error) {
+	value, err := e.operand.eval(env)
+	if err != nil || isBlank(value) {
+		return nil, err
+	}
+	t, err := env.query.toTime(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", e, err)
+	}
+
+	switch e.unit {
+	case "YEAR":
+		return int64(t.Year()), nil
+	case "QUARTER":
+		return int64(t.Month()-1)/3 + 1, nil
+	case "MONTH":
+		return int64(t.Month()), nil
+	case "WEEK":
+		_, week := t.ISOWeek()
+		return int64(week), nil
+	case "DAY":
+		return int64(t.Day()), nil
+	case "HOUR":
+		return int64(t.Hour()), nil
+	case "MINUTE":
+		return int64(t.Minute()), nil
+	case "SECOND":
+		return int64(t.Second()), nil
+	case "DOW":
+		return int64(t.Weekday()), nil
+	case "DOY":
+		return int64(t.YearDay()), nil
+	}
+	return t.Unix(), nil
+}
+
+func (e *extractExpr) String() string {
+	return "EXTRACT(" + e.unit + " FROM " + e.operand.String() + ")"
+}
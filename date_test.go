@@ -0,0 +1,206 @@
+package sheetsql
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQuery_toTime(t *testing.T) {
+	date := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		layouts  []string
+		value    interface{}
+		expected time.Time
+		wantErr  string
+	}{
+		{name: "iso date", value: "2024-03-01", expected: date(2024, 3, 1, 0, 0)},
+		{name: "iso timestamp", value: "2024-03-01 10:30:00", expected: date(2024, 3, 1, 10, 30)},
+		{name: "rfc 3339", value: "2024-03-01T10:30:00+02:00", expected: date(2024, 3, 1, 8, 30)},
+		{name: "month first", value: "3/1/2024", expected: date(2024, 3, 1, 0, 0)},
+		{name: "serial", value: "45352", expected: date(2024, 3, 1, 0, 0)},
+		{name: "serial with time", value: 45352.75, expected: date(2024, 3, 1, 18, 0)},
+		{name: "integer serial", value: 45352, expected: date(2024, 3, 1, 0, 0)},
+		{name: "day first layout", layouts: []string{"2/1/2006"}, value: "3/1/2024", expected: date(2024, 1, 3, 0, 0)},
+		{name: "iso with custom layouts", layouts: []string{"2/1/2006"}, value: "2024-03-01", expected: date(2024, 3, 1, 0, 0)},
+		{name: "not a date", value: "soon", wantErr: `"soon" is not a date`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{}
+			client.SetDateLayouts(tt.layouts...)
+			if tt.layouts == nil {
+				client.dateLayouts = nil
+			}
+
+			got, err := client.From("Orders").toTime(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("toTime() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("toTime() error = %v", err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("toTime() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSQLParser_Query_Dates(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 4, 5, 0, time.UTC)
+
+	mock := NewMockSheetsService()
+	mock.AddSheetData("Orders", [][]interface{}{
+		{"ID", "Created", "Shipped"},
+		{"1", "2024-01-15", "2024-01-20 09:30:00"},
+		{"2", "3/1/2024", "45355"},
+		{"3", "45352.75", ""},
+		{"4", "12/31/2023"},
+	})
+	mock.AddSheetData("Tracking", [][]interface{}{
+		{"ID", "Created"},
+		{"1", "2024-01-15"},
+		{"2", "N/A"},
+		{"3", "2023-12-31"},
+	})
+	client := mock.Client("test-id")
+	client.clock = func() time.Time { return now }
+	parser := NewSQLParser(client)
+
+	day := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		sql      string
+		expected [][]interface{}
+		wantErr  string
+	}{
+		{
+			name:     "compare with date literal",
+			sql:      "SELECT ID FROM Orders WHERE Created >= DATE '2024-01-01'",
+			expected: [][]interface{}{{"1"}, {"2"}, {"3"}},
+		},
+		{
+			name:     "between",
+			sql:      "SELECT ID FROM Orders WHERE Created BETWEEN DATE '2024-02-01' AND TIMESTAMP '2024-03-01 12:00:00'",
+			expected: [][]interface{}{{"2"}},
+		},
+		{
+			name:     "in",
+			sql:      "SELECT ID FROM Orders WHERE Created IN (DATE '2024-01-15', DATE '2023-12-31')",
+			expected: [][]interface{}{{"1"}, {"4"}},
+		},
+		{
+			name:     "date drops the time",
+			sql:      "SELECT ID FROM Orders WHERE DATE(Created) = DATE '2024-03-01'",
+			expected: [][]interface{}{{"2"}, {"3"}},
+		},
+		{
+			name:     "two columns",
+			sql:      "SELECT ID FROM Orders WHERE CAST(Shipped AS TIMESTAMP) > CAST(Created AS TIMESTAMP)",
+			expected: [][]interface{}{{"1"}, {"2"}},
+		},
+		{
+			name:     "relative to now",
+			sql:      "SELECT ID, NOW() FROM Orders WHERE Created > DATE_ADD(DATE(NOW()), -30, 'day')",
+			expected: [][]interface{}{{"2", now}, {"3", now}},
+		},
+		{
+			name:     "truncate and add",
+			sql:      "SELECT DATE_TRUNC('month', Created), DATE_TRUNC('week', Created), DATE_TRUNC('quarter', Created), DATE_ADD(Created, 1, 'month'), DATE_ADD(Created, -2, 'hour') FROM Orders WHERE ID = 3",
+			expected: [][]interface{}{{day(2024, 3, 1), day(2024, 2, 26), day(2024, 1, 1), time.Date(2024, 4, 1, 18, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 16, 0, 0, 0, time.UTC)}},
+		},
+		{
+			name:     "extract",
+			sql:      "SELECT EXTRACT(YEAR FROM Created), EXTRACT(QUARTER FROM Created), EXTRACT(MONTH FROM Created), EXTRACT(DAY FROM Created), EXTRACT(HOUR FROM Created), EXTRACT(DOW FROM Created), EXTRACT(DOY FROM Created), EXTRACT(WEEK FROM Created) FROM Orders WHERE ID = 3",
+			expected: [][]interface{}{{int64(2024), int64(1), int64(3), int64(1), int64(18), int64(5), int64(61), int64(9)}},
+		},
+		{
+			name:     "group by month",
+			sql:      "SELECT DATE_TRUNC('month', Created) AS Month, COUNT(*) FROM Orders GROUP BY DATE_TRUNC('month', Created) ORDER BY Month",
+			expected: [][]interface{}{{day(2023, 12, 1), 1}, {day(2024, 1, 1), 1}, {day(2024, 3, 1), 2}},
+		},
+		{
+			name:     "order by date",
+			sql:      "SELECT ID FROM Orders ORDER BY CAST(Created AS TIMESTAMP) DESC",
+			expected: [][]interface{}{{"3"}, {"2"}, {"1"}, {"4"}},
+		},
+		{
+			name:     "empty cells are null",
+			sql:      "SELECT ID FROM Orders WHERE Shipped < NOW() OR Shipped >= NOW()",
+			expected: [][]interface{}{{"1"}, {"2"}},
+		},
+		{
+			name:     "cells that are not dates are null",
+			sql:      "SELECT ID FROM Tracking WHERE Created >= DATE '2024-01-01' OR Created < DATE '2024-01-01'",
+			expected: [][]interface{}{{"1"}, {"3"}},
+		},
+		{
+			name:     "not a date",
+			sql:      "SELECT ID FROM Orders WHERE ID || 'x' > DATE '2024-01-01'",
+			expected: nil,
+		},
+		{
+			name:    "unknown unit",
+			sql:     "SELECT DATE_TRUNC('fortnight', Created) FROM Orders",
+			wantErr: `DATE_TRUNC: unknown unit "fortnight"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := parser.parseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("parseSQL() error = %v", err)
+			}
+
+			_, rows, err := query.rows()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("rows() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rows() error = %v", err)
+			}
+			if !reflect.DeepEqual(rows, tt.expected) {
+				t.Errorf("rows = %v, expected %v", rows, tt.expected)
+			}
+		})
+	}
+
+	type Order struct {
+		ID      int       `sheet:"ID"`
+		Created time.Time `sheet:"Created"`
+	}
+
+	var orders []Order
+	err := client.From("Orders").Where("Created", "<", day(2024, 3, 1)).Get(&orders)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	expected := []Order{{1, day(2024, 1, 15)}, {4, day(2023, 12, 31)}}
+	if !reflect.DeepEqual(orders, expected) {
+		t.Errorf("Get() = %v, expected %v", orders, expected)
+	}
+
+	_, rows, err := client.From("Tracking").Select("ID").Where("Created", ">=", day(2024, 1, 1)).rows()
+	if err != nil {
+		t.Fatalf("rows() error = %v", err)
+	}
+	if expected := [][]interface{}{{"1"}}; !reflect.DeepEqual(rows, expected) {
+		t.Errorf("rows = %v, expected %v", rows, expected)
+	}
+}
@@ -0,0 +1,283 @@
+package sheetsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/api/option"
+)
+
+func init() {
+	sql.Register("sheetsql", &Driver{})
+}
+
+// Driver is the database/sql driver registered as "sheetsql". Data source
+// names are semicolon-separated key=value pairs:
+//
+//	db, err := sql.Open("sheetsql", "spreadsheet=ID;credentials=credentials.json")
+//
+// The spreadsheet key is required; credentials, apikey and endpoint map to
+// the matching google.golang.org/api/option settings.
+type Driver struct{}
+
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	connector, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return connector.Connect(context.Background())
+}
+
+func (d *Driver) OpenConnector(dsn string) (driver.Connector, error) {
+	spreadsheetID, opts, err := parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(context.Background(), spreadsheetID, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConnector(client), nil
+}
+
+func parseDSN(dsn string) (string, []option.ClientOption, error) {
+	var spreadsheetID string
+	var opts []option.ClientOption
+
+	for _, part := range strings.Split(dsn, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return "", nil, fmt.Errorf("invalid DSN element: %s", part)
+		}
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "spreadsheet":
+			spreadsheetID = value
+		case "credentials":
+			opts = append(opts, option.WithCredentialsFile(value))
+		case "apikey":
+			opts = append(opts, option.WithAPIKey(value))
+		case "endpoint":
+			opts = append(opts, option.WithEndpoint(value), option.WithoutAuthentication())
+		default:
+			return "", nil, fmt.Errorf("unknown DSN key: %s", key)
+		}
+	}
+
+	if spreadsheetID == "" {
+		return "", nil, fmt.Errorf("DSN is missing the spreadsheet key")
+	}
+
+	return spreadsheetID, opts, nil
+}
+
+// NewConnector returns a connector for sql.OpenDB that runs statements
+// against an existing client, such as one built with NewClientWithBackend.
+func NewConnector(client *Client) driver.Connector {
+	return &connector{parser: NewSQLParser(client)}
+}
+
+// connector shares one parser, and so its statement cache, between its
+// connections.
+type connector struct {
+	parser *SQLParser
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &conn{parser: c.parser}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return &Driver{}
+}
+
+type conn struct {
+	parser *SQLParser
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	if _, err := c.parser.parse(query); err != nil {
+		return nil, fmt.Errorf("failed to parse SQL: %w", err)
+	}
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return nil, errors.New("sheetsql: transactions are not supported")
+}
+
+// CheckNamedValue accepts arguments as they are so that INSERT and UPDATE
+// can take the row struct the same way SQLParser.Insert and Update do.
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	return nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	stmt, err := c.parser.parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SQL: %w", err)
+	}
+
+	if _, ok := stmt.(*selectStmt); ok {
+		if _, err := c.QueryContext(ctx, query, args); err != nil {
+			return nil, err
+		}
+		return driver.RowsAffected(0), nil
+	}
+
+	n, err := execStatement(stmt, bindArgs(args))
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(n), nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	sel, err := c.parser.parseSelectStmt(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SQL: %w", err)
+	}
+
+	exec, err := newBoundExecution(sel, bindArgs(args))
+	if err != nil {
+		return nil, err
+	}
+
+	headers, values, err := sel.query.run(exec, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rows{columns: headers, values: values}, nil
+}
+
+// bindArgs turns driver arguments into the arguments placeholders are bound
+// to, keeping the names given with sql.Named.
+func bindArgs(args []driver.NamedValue) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+		if arg.Name != "" {
+			values[i] = sql.Named(arg.Name, arg.Value)
+		}
+	}
+	return values
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, namedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, namedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+type rows struct {
+	columns []string
+	values  [][]interface{}
+	pos     int
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	r.pos = len(r.values)
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.values) {
+		return io.EOF
+	}
+
+	row := r.values[r.pos]
+	r.pos++
+
+	for i := range dest {
+		if i < len(row) {
+			dest[i] = driverValue(row[i])
+		} else {
+			dest[i] = nil
+		}
+	}
+
+	return nil
+}
+
+// driverValue converts a cell to one of the types database/sql expects from
+// a driver.
+func driverValue(v interface{}) driver.Value {
+	switch v := v.(type) {
+	case nil, string, int64, float64, bool, []byte, time.Time:
+		return v
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	}
+	return fmt.Sprintf("%v", v)
+}
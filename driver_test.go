@@ -0,0 +1,170 @@
+package sheetsql
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func TestDriver_Query(t *testing.T) {
+	db := sql.OpenDB(NewConnector(SetupTestData().Client("test-id")))
+	defer db.Close()
+
+	rows, err := db.Query("SELECT * FROM Users WHERE City = 'New York'")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		t.Fatalf("Columns() error = %v", err)
+	}
+	if !reflect.DeepEqual(columns, []string{"ID", "Name", "Email", "Age", "City"}) {
+		t.Errorf("Columns() = %v", columns)
+	}
+
+	var names []string
+	for rows.Next() {
+		var id, age int
+		var name, email, city string
+		if err := rows.Scan(&id, &name, &email, &age, &city); err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows error = %v", err)
+	}
+
+	if !reflect.DeepEqual(names, []string{"John Doe", "Alice Brown"}) {
+		t.Errorf("names = %v, expected John Doe and Alice Brown", names)
+	}
+}
+
+func TestDriver_QueryArgs(t *testing.T) {
+	db := sql.OpenDB(NewConnector(SetupTestData().Client("test-id")))
+	defer db.Close()
+
+	var name string
+	err := db.QueryRow("SELECT Name FROM Users WHERE City = :city AND Age < :age", sql.Named("city", "New York"), sql.Named("age", 30)).Scan(&name)
+	if err != nil {
+		t.Fatalf("QueryRow() error = %v", err)
+	}
+	if name != "Alice Brown" {
+		t.Errorf("name = %q, expected Alice Brown", name)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM Users WHERE Age > $1", 25).Scan(&count); err != nil {
+		t.Fatalf("QueryRow() error = %v", err)
+	}
+	if count != 3 {
+		t.Errorf("count = %d, expected 3", count)
+	}
+
+	if _, err := db.Query("SELECT * FROM Users WHERE Age > ?"); err == nil {
+		t.Error("Query() without an argument for ? succeeded")
+	}
+}
+
+func TestDriver_Exec(t *testing.T) {
+	mock := SetupTestData()
+	db := sql.OpenDB(NewConnector(mock.Client("test-id")))
+	defer db.Close()
+
+	type user struct {
+		ID   int    `sheet:"ID"`
+		Name string `sheet:"Name"`
+		City string `sheet:"City"`
+	}
+
+	tests := []struct {
+		name     string
+		sql      string
+		args     []interface{}
+		affected int64
+	}{
+		{"insert", "INSERT INTO Users", []interface{}{user{ID: 6, Name: "Dana White", City: "Boston"}}, 1},
+		{"update", "UPDATE Users SET City = 'Boston' WHERE ID = 2", []interface{}{user{ID: 2, Name: "Jane Smith", City: "Boston"}}, 1},
+		{"update from SQL", "UPDATE Users SET Age = Age + 1 WHERE City = ?", []interface{}{"New York"}, 2},
+		{"insert values", "INSERT INTO Users (ID, Name, City) VALUES (7, ?, 'Boston'), (8, 'Finn', 'Boston')", []interface{}{"Eve"}, 2},
+		{"delete", "DELETE FROM Users WHERE City = ?", []interface{}{"Boston"}, 5},
+		{"delete nothing", "DELETE FROM Users WHERE City = 'Boston'", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := db.Exec(tt.sql, tt.args...)
+			if err != nil {
+				t.Fatalf("Exec() error = %v", err)
+			}
+
+			affected, err := result.RowsAffected()
+			if err != nil {
+				t.Fatalf("RowsAffected() error = %v", err)
+			}
+			if affected != tt.affected {
+				t.Errorf("RowsAffected() = %d, expected %d", affected, tt.affected)
+			}
+		})
+	}
+
+	if rows := mock.GetSheetData("Users"); len(rows) != 4 {
+		t.Errorf("expected 4 rows including header, got %d", len(rows))
+	}
+}
+
+func TestDriver_parseDSN(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsn     string
+		id      string
+		opts    int
+		wantErr bool
+	}{
+		{"spreadsheet and credentials", "spreadsheet=abc;credentials=creds.json", "abc", 1, false},
+		{"endpoint", "spreadsheet=abc; endpoint=http://localhost:8080", "abc", 2, false},
+		{"missing spreadsheet", "credentials=creds.json", "", 0, true},
+		{"unknown key", "spreadsheet=abc;color=blue", "", 0, true},
+		{"malformed element", "spreadsheet", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, opts, err := parseDSN(tt.dsn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDSN() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if id != tt.id || len(opts) != tt.opts {
+				t.Errorf("parseDSN() = %q with %d options, expected %q with %d", id, len(opts), tt.id, tt.opts)
+			}
+		})
+	}
+}
+
+func TestDriver_Open(t *testing.T) {
+	emu := NewEmulator()
+	defer emu.Close()
+	emu.SetSheet("Users", SetupTestData().GetSheetData("Users"))
+
+	db, err := sql.Open("sheetsql", "spreadsheet=test-id;endpoint="+emu.URL)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+
+	var count int
+	rows, err := db.Query("SELECT * FROM Users WHERE Age > 26")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	for rows.Next() {
+		count++
+	}
+	rows.Close()
+
+	if count != 3 {
+		t.Errorf("expected 3 users over 26, got %d", count)
+	}
+}
@@ -0,0 +1,230 @@
+package sheetsql
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// Emulator is a local HTTP server implementing the subset of the Sheets v4
+// REST API used by sheetsql, backed by a MemoryBackend. Point a Client at it
+// with the options returned by ClientOptions:
+//
+//	emu := sheetsql.NewEmulator()
+//	defer emu.Close()
+//	emu.SetSheet("Users", rows)
+//	client, err := sheetsql.NewClient(ctx, "any-id", emu.ClientOptions()...)
+type Emulator struct {
+	*MemoryBackend
+	URL    string
+	server *httptest.Server
+}
+
+func NewEmulator() *Emulator {
+	e := &Emulator{
+		MemoryBackend: NewMemoryBackend(),
+	}
+	e.server = httptest.NewServer(http.HandlerFunc(e.serveHTTP))
+	e.URL = e.server.URL
+	return e
+}
+
+func (e *Emulator) Close() {
+	e.server.Close()
+}
+
+// ClientOptions returns the options that make NewClient talk to the emulator.
+func (e *Emulator) ClientOptions() []option.ClientOption {
+	return []option.ClientOption{
+		option.WithEndpoint(e.URL),
+		option.WithoutAuthentication(),
+	}
+}
+
+func (e *Emulator) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v4/spreadsheets/")
+	if path == r.URL.Path || path == "" {
+		writeEmulatorError(w, http.StatusNotFound, "unknown method: %s %s", r.Method, r.URL.Path)
+		return
+	}
+
+	spreadsheetID, rest := path, ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		spreadsheetID, rest = path[:i], path[i+1:]
+	}
+
+	switch {
+	case rest == "" && strings.HasSuffix(spreadsheetID, ":batchUpdate") && r.Method == http.MethodPost:
+		e.batchUpdate(w, r, strings.TrimSuffix(spreadsheetID, ":batchUpdate"))
+	case rest == "" && r.Method == http.MethodGet:
+		e.getSpreadsheet(w, spreadsheetID)
+	case rest == "values:batchGet" && r.Method == http.MethodGet:
+		e.batchGetValues(w, r, spreadsheetID)
+	case strings.HasPrefix(rest, "values/"):
+		readRange := strings.TrimPrefix(rest, "values/")
+		switch {
+		case strings.HasSuffix(readRange, ":append") && r.Method == http.MethodPost:
+			e.appendValues(w, r, spreadsheetID, strings.TrimSuffix(readRange, ":append"))
+		case r.Method == http.MethodGet:
+			e.getValues(w, r, spreadsheetID, readRange)
+		case r.Method == http.MethodPut:
+			e.updateValues(w, r, spreadsheetID, readRange)
+		default:
+			writeEmulatorError(w, http.StatusNotFound, "unknown method: %s %s", r.Method, r.URL.Path)
+		}
+	default:
+		writeEmulatorError(w, http.StatusNotFound, "unknown method: %s %s", r.Method, r.URL.Path)
+	}
+}
+
+func (e *Emulator) getValues(w http.ResponseWriter, r *http.Request, spreadsheetID, readRange string) {
+	values, err := e.ReadRange(spreadsheetID, readRange)
+	if err != nil {
+		writeEmulatorError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	if r.URL.Query().Get("valueRenderOption") != "UNFORMATTED_VALUE" {
+		values = formatValues(values)
+	}
+
+	writeEmulatorJSON(w, &sheets.ValueRange{
+		Range:          readRange,
+		MajorDimension: "ROWS",
+		Values:         values,
+	})
+}
+
+func (e *Emulator) batchGetValues(w http.ResponseWriter, r *http.Request, spreadsheetID string) {
+	readRanges := r.URL.Query()["ranges"]
+	values, err := e.ReadRanges(spreadsheetID, readRanges)
+	if err != nil {
+		writeEmulatorError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	resp := &sheets.BatchGetValuesResponse{SpreadsheetId: spreadsheetID}
+	for i, readRange := range readRanges {
+		if r.URL.Query().Get("valueRenderOption") != "UNFORMATTED_VALUE" {
+			values[i] = formatValues(values[i])
+		}
+		resp.ValueRanges = append(resp.ValueRanges, &sheets.ValueRange{
+			Range:          readRange,
+			MajorDimension: "ROWS",
+			Values:         values[i],
+		})
+	}
+
+	writeEmulatorJSON(w, resp)
+}
+
+func (e *Emulator) appendValues(w http.ResponseWriter, r *http.Request, spreadsheetID, writeRange string) {
+	var valueRange sheets.ValueRange
+	if err := json.NewDecoder(r.Body).Decode(&valueRange); err != nil {
+		writeEmulatorError(w, http.StatusBadRequest, "invalid request body: %v", err)
+		return
+	}
+
+	if err := e.AppendRows(spreadsheetID, writeRange, valueRange.Values); err != nil {
+		writeEmulatorError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	writeEmulatorJSON(w, &sheets.AppendValuesResponse{
+		SpreadsheetId: spreadsheetID,
+		TableRange:    writeRange,
+	})
+}
+
+func (e *Emulator) updateValues(w http.ResponseWriter, r *http.Request, spreadsheetID, updateRange string) {
+	var valueRange sheets.ValueRange
+	if err := json.NewDecoder(r.Body).Decode(&valueRange); err != nil {
+		writeEmulatorError(w, http.StatusBadRequest, "invalid request body: %v", err)
+		return
+	}
+
+	if err := e.UpdateRange(spreadsheetID, updateRange, valueRange.Values); err != nil {
+		writeEmulatorError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	writeEmulatorJSON(w, &sheets.UpdateValuesResponse{
+		SpreadsheetId: spreadsheetID,
+		UpdatedRange:  updateRange,
+		UpdatedRows:   int64(len(valueRange.Values)),
+	})
+}
+
+func (e *Emulator) getSpreadsheet(w http.ResponseWriter, spreadsheetID string) {
+	props, err := e.SheetProperties(spreadsheetID)
+	if err != nil {
+		writeEmulatorError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	spreadsheet := &sheets.Spreadsheet{
+		SpreadsheetId: spreadsheetID,
+	}
+	for _, prop := range props {
+		spreadsheet.Sheets = append(spreadsheet.Sheets, &sheets.Sheet{Properties: prop})
+	}
+
+	writeEmulatorJSON(w, spreadsheet)
+}
+
+func (e *Emulator) batchUpdate(w http.ResponseWriter, r *http.Request, spreadsheetID string) {
+	var req sheets.BatchUpdateSpreadsheetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeEmulatorError(w, http.StatusBadRequest, "invalid request body: %v", err)
+		return
+	}
+
+	if err := e.BatchUpdate(spreadsheetID, &req); err != nil {
+		writeEmulatorError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	resp := &sheets.BatchUpdateSpreadsheetResponse{
+		SpreadsheetId: spreadsheetID,
+	}
+	for range req.Requests {
+		resp.Replies = append(resp.Replies, &sheets.Response{})
+	}
+
+	writeEmulatorJSON(w, resp)
+}
+
+// formatValues renders cells as strings, the way the Sheets API does for the
+// default FORMATTED_VALUE render option.
+func formatValues(values [][]interface{}) [][]interface{} {
+	formatted := make([][]interface{}, len(values))
+	for i, row := range values {
+		formatted[i] = make([]interface{}, len(row))
+		for j, value := range row {
+			formatted[i][j] = fmt.Sprintf("%v", value)
+		}
+	}
+	return formatted
+}
+
+func writeEmulatorJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeEmulatorError(w http.ResponseWriter, code int, format string, args ...interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": fmt.Sprintf(format, args...),
+			"status":  http.StatusText(code),
+		},
+	})
+}
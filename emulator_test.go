@@ -0,0 +1,60 @@
+package sheetsql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestEmulator(t *testing.T) {
+	emu := NewEmulator()
+	defer emu.Close()
+
+	emu.SetSheet("Users", [][]interface{}{
+		{"ID", "Name"},
+		{1, "John"},
+	})
+
+	srv, err := sheets.NewService(context.Background(), emu.ClientOptions()...)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	resp, err := srv.Spreadsheets.Values.Get("test-id", "Users!A:Z").Do()
+	if err != nil {
+		t.Fatalf("values.get error = %v", err)
+	}
+	expected := [][]interface{}{{"ID", "Name"}, {"1", "John"}}
+	if !reflect.DeepEqual(resp.Values, expected) {
+		t.Errorf("values.get = %v, expected %v", resp.Values, expected)
+	}
+
+	emu.SetSheet("Teams", [][]interface{}{{"Team"}, {"red"}})
+	batch, err := srv.Spreadsheets.Values.BatchGet("test-id").Ranges("Teams!A:Z", "Users!A1:A1").Do()
+	if err != nil {
+		t.Fatalf("values.batchGet error = %v", err)
+	}
+	if len(batch.ValueRanges) != 2 ||
+		!reflect.DeepEqual(batch.ValueRanges[0].Values, [][]interface{}{{"Team"}, {"red"}}) ||
+		!reflect.DeepEqual(batch.ValueRanges[1].Values, [][]interface{}{{"ID"}}) {
+		t.Errorf("values.batchGet = %+v", batch.ValueRanges)
+	}
+
+	spreadsheet, err := srv.Spreadsheets.Get("test-id").Do()
+	if err != nil {
+		t.Fatalf("spreadsheets.get error = %v", err)
+	}
+	if len(spreadsheet.Sheets) != 2 || spreadsheet.Sheets[0].Properties.Title != "Users" {
+		t.Errorf("spreadsheets.get = %+v", spreadsheet.Sheets)
+	}
+
+	_, err = srv.Spreadsheets.Values.Get("test-id", "Missing!A:Z").Do()
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != 400 {
+		t.Errorf("values.get on a missing sheet error = %v, expected a 400 API error", err)
+	}
+}
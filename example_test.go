@@ -27,7 +27,7 @@ func ExampleClient_fluent() {
 	var users []User
 	err = client.From("Users").
 		Where("Age", ">", 18).
-		Where("Name", "LIKE", "John").
+		Where("Name", "LIKE", "John%").
 		Limit(10).
 		Get(&users)
 
@@ -51,7 +51,7 @@ func ExampleClient_sql() {
 	parser := sheetsql.NewSQLParser(client)
 
 	var users []User
-	err = parser.Query("SELECT * FROM Users WHERE Age > 18 AND Name LIKE 'John' LIMIT 10", &users)
+	err = parser.Query("SELECT * FROM Users WHERE Age > 18 AND Name LIKE 'John%' LIMIT 10", &users)
 	if err != nil {
 		log.Fatal(err)
 	}
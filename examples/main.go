@@ -75,11 +75,11 @@ func main() {
 		}
 	}
 
-	// Example 4: LIKE operator
-	fmt.Println("\n4. LIKE operator - Users with 'John' in name:")
+	// Example 4: CONTAINS operator
+	fmt.Println("\n4. CONTAINS operator - Users with 'John' in name:")
 	var johnUsers []User
 	err = client.From("Sheet1").
-		Where("Name", "LIKE", "John").
+		Where("Name", "CONTAINS", "John").
 		Get(&johnUsers)
 	if err != nil {
 		log.Printf("Error: %v", err)
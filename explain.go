@@ -0,0 +1,392 @@
+package sheetsql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Plan describes how a statement runs, worked out without reading any
+// sheets: the stages rows pass through and the requests made to the Sheets
+// API. The API cannot filter rows, so every sheet is read whole and every
+// filter runs client-side.
+type Plan struct {
+	// Root is the final stage; rows flow to it from the leaves.
+	Root *PlanNode
+
+	// Requests are the API requests made whatever the data, in order.
+	// ConditionalRequests are made after them, each at most once, when a
+	// row first reaches the subquery that needs them, so an UPDATE or
+	// DELETE that matches no rows may not make them. RowRequests are made
+	// again for every row the statement changes.
+	Requests            []string
+	ConditionalRequests []string
+	RowRequests         []string
+}
+
+// PlanNode is one stage of a plan, fed by the rows of its children.
+type PlanNode struct {
+	Op       string
+	Detail   string
+	Children []*PlanNode
+}
+
+// String returns the plan as an indented tree followed by its requests.
+func (p *Plan) String() string {
+	var sb strings.Builder
+
+	var write func(n *PlanNode, depth int)
+	write = func(n *PlanNode, depth int) {
+		sb.WriteString(strings.Repeat("  ", depth) + n.Op)
+		if n.Detail != "" {
+			sb.WriteString(": " + n.Detail)
+		}
+		sb.WriteByte('\n')
+		for _, child := range n.Children {
+			write(child, depth+1)
+		}
+	}
+	write(p.Root, 0)
+
+	fmt.Fprintf(&sb, "API requests: %d", len(p.Requests))
+	if len(p.ConditionalRequests) > 0 {
+		fmt.Fprintf(&sb, ", plus up to %d if needed", len(p.ConditionalRequests))
+	}
+	if len(p.RowRequests) > 0 {
+		fmt.Fprintf(&sb, ", plus %d per changed row", len(p.RowRequests))
+	}
+	sb.WriteByte('\n')
+	for _, r := range p.Requests {
+		sb.WriteString("  " + r + "\n")
+	}
+	for _, r := range p.ConditionalRequests {
+		sb.WriteString("  if needed: " + r + "\n")
+	}
+	for _, r := range p.RowRequests {
+		sb.WriteString("  per changed row: " + r + "\n")
+	}
+	return sb.String()
+}
+
+// Explain returns the plan of the query as Get runs it, without reading the
+// sheet.
+func (q *Query) Explain() (*Plan, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	plan := &Plan{Root: newPlanner().query(q)}
+	if ranges := q.sheetRanges(); len(ranges) > 0 {
+		plan.Requests = []string{readRequest(ranges)}
+	}
+	return plan, nil
+}
+
+// Explain returns the plan of a SELECT, INSERT, UPDATE or DELETE statement
+// without running it:
+//
+//	plan, err := parser.Explain("SELECT * FROM Orders JOIN Users ON Users.ID = Orders.UserID")
+//	...
+//	fmt.Print(plan)
+func (p *SQLParser) Explain(sql string) (*Plan, error) {
+	stmt, err := p.parse(sql)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SQL: %w", err)
+	}
+
+	pl := newPlanner()
+	switch stmt := stmt.(type) {
+	case *selectStmt:
+		return stmt.query.Explain()
+	case *insertStmt:
+		return pl.insert(stmt), nil
+	case *updateStmt:
+		detail := stmt.query.sheetName
+		if len(stmt.assignments) > 0 {
+			set := make([]string, len(stmt.assignments))
+			for i, a := range stmt.assignments {
+				set[i] = quoteIdentifier(a.column) + " = " + a.value.String()
+			}
+			detail += " SET " + strings.Join(set, ", ")
+		}
+
+		values := make([]expr, len(stmt.assignments))
+		for i, a := range stmt.assignments {
+			values[i] = a.value
+		}
+		plan := pl.write("Update", detail, stmt.query, values...)
+		plan.RowRequests = []string{fmt.Sprintf("UpdateRange %s!A<row>:Z<row>", stmt.query.sheetName)}
+		return plan, nil
+	case *deleteStmt:
+		plan := pl.write("Delete", stmt.query.sheetName, stmt.query)
+		plan.RowRequests = []string{"SheetProperties", "BatchUpdate (delete row)"}
+		return plan, nil
+	}
+	return nil, fmt.Errorf("cannot explain %T", stmt)
+}
+
+// readRequest describes the request execution.read makes for ranges.
+func readRequest(ranges []string) string {
+	if len(ranges) == 1 {
+		return "ReadRange " + ranges[0]
+	}
+	return "ReadRanges " + strings.Join(ranges, ", ")
+}
+
+// planner builds plan nodes, showing the plan of a common table expression
+// only where it is first read since it is computed once.
+type planner struct {
+	ctes      map[*cte]bool
+	recursing map[*cte]bool
+}
+
+func newPlanner() *planner {
+	return &planner{ctes: make(map[*cte]bool), recursing: make(map[*cte]bool)}
+}
+
+// query returns the stages of a SELECT in the order run applies them.
+func (pl *planner) query(q *Query) *PlanNode {
+	node := pl.table(q.sheetName, q.cte, q.alias)
+	if q.pivot != nil {
+		op := "Pivot"
+		if q.pivot.unpivot {
+			op = "Unpivot"
+		}
+		node = &PlanNode{Op: op, Detail: q.pivot.String(), Children: []*PlanNode{node}}
+	}
+
+	for _, j := range q.joins {
+		right := pl.table(j.sheet, j.cte, j.alias)
+		if j.on == nil {
+			node = &PlanNode{Op: "Cross Join", Children: []*PlanNode{node, right}}
+			continue
+		}
+		// The = conditions between the two sides are the hash keys; the
+		// rest are checked for each pair of rows with equal keys.
+		node = &PlanNode{
+			Op:       "Hash Join",
+			Detail:   j.kind + " ON " + j.on.String(),
+			Children: append([]*PlanNode{node, right}, pl.subqueries(j.on)...),
+		}
+	}
+
+	if q.where != nil {
+		node = pl.stage("Filter (client-side)", q.where.String(), node, q.where)
+	}
+
+	if q.grouped() {
+		detail := "all rows"
+		if len(q.groupBy) > 0 {
+			detail = "GROUP BY " + joinExprs(q.groupBy)
+		}
+		node = pl.stage("Aggregate", detail, node)
+		if q.having != nil {
+			node = pl.stage("Filter (client-side)", "HAVING "+q.having.String(), node, q.having)
+		}
+	}
+
+	var columns []expr
+	for _, item := range q.columns {
+		if !item.star {
+			columns = append(columns, item.expr)
+		}
+	}
+	ordering := make([]expr, len(q.orderBy))
+	for i, item := range q.orderBy {
+		ordering[i] = item.expr
+	}
+	if windows := windowExprs(append(columns, ordering...)); len(windows) > 0 {
+		exprs := make([]expr, len(windows))
+		for i, w := range windows {
+			exprs[i] = w
+		}
+		node = pl.stage("Window", joinExprs(exprs), node)
+	}
+
+	compound := len(q.setOps) > 0
+	if !compound {
+		node = pl.sort(q, node)
+		if !q.distinct {
+			node = limitStage(q, node)
+		}
+	}
+
+	projection := "*"
+	if len(q.columns) > 0 {
+		items := make([]string, len(q.columns))
+		for i, item := range q.columns {
+			items[i] = item.String()
+		}
+		projection = strings.Join(items, ", ")
+	}
+	node = pl.stage("Project", projection, node, columns...)
+	if q.distinct {
+		node = &PlanNode{Op: "Distinct", Children: []*PlanNode{node}}
+	}
+
+	for _, op := range q.setOps {
+		node = &PlanNode{Op: setOpName(op), Children: []*PlanNode{node, pl.query(op.query)}}
+	}
+	if compound {
+		node = pl.sort(q, node)
+	}
+	if compound || q.distinct {
+		node = limitStage(q, node)
+	}
+	return node
+}
+
+// table returns the stage reading a sheet or a common table expression.
+func (pl *planner) table(sheet string, c *cte, alias string) *PlanNode {
+	node := &PlanNode{Op: "Scan", Detail: fmt.Sprintf("%s!A:Z", sheet)}
+	if c != nil {
+		node = &PlanNode{Op: "CTE Scan", Detail: quoteIdentifier(c.name)}
+		switch {
+		case pl.recursing[c]:
+			node.Detail += " (previous round)"
+		case pl.ctes[c]:
+			node.Detail += " (reused)"
+		default:
+			pl.ctes[c] = true
+			node.Children = []*PlanNode{pl.cte(c)}
+		}
+	}
+
+	if alias != "" {
+		node.Detail += " AS " + quoteIdentifier(alias)
+	}
+	return node
+}
+
+// cte returns the stages computing a common table expression.
+func (pl *planner) cte(c *cte) *PlanNode {
+	node := pl.query(c.query)
+
+	pl.recursing[c] = true
+	for _, op := range c.recursive {
+		node = &PlanNode{
+			Op:       "Recursive " + setOpName(op),
+			Detail:   fmt.Sprintf("until no new rows, at most %d rounds", maxRecursion),
+			Children: []*PlanNode{node, pl.query(op.query)},
+		}
+	}
+	delete(pl.recursing, c)
+
+	return node
+}
+
+// stage returns a stage evaluating exprs over the rows of input, with the
+// plans of any subqueries in exprs as further children.
+func (pl *planner) stage(op, detail string, input *PlanNode, exprs ...expr) *PlanNode {
+	return &PlanNode{Op: op, Detail: detail, Children: append([]*PlanNode{input}, pl.subqueries(exprs...)...)}
+}
+
+// subqueries returns the plans of the subqueries in exprs. Their results
+// are reused for every row unless they read a column of the outer row.
+func (pl *planner) subqueries(exprs ...expr) []*PlanNode {
+	var nodes []*PlanNode
+	for _, sub := range exprSubqueries(exprs) {
+		nodes = append(nodes, &PlanNode{Op: "Subquery", Children: []*PlanNode{pl.query(sub)}})
+	}
+	return nodes
+}
+
+func (pl *planner) sort(q *Query, input *PlanNode) *PlanNode {
+	if len(q.orderBy) == 0 {
+		return input
+	}
+	items := make([]string, len(q.orderBy))
+	exprs := make([]expr, len(q.orderBy))
+	for i, item := range q.orderBy {
+		items[i], exprs[i] = item.String(), item.expr
+	}
+	return pl.stage("Sort", strings.Join(items, ", "), input, exprs...)
+}
+
+func limitStage(q *Query, input *PlanNode) *PlanNode {
+	var parts []string
+	if q.limit > 0 {
+		parts = append(parts, fmt.Sprintf("LIMIT %d", q.limit))
+	}
+	if q.offset > 0 {
+		parts = append(parts, fmt.Sprintf("OFFSET %d", q.offset))
+	}
+	if len(parts) == 0 {
+		return input
+	}
+	return &PlanNode{Op: "Limit", Detail: strings.Join(parts, " "), Children: []*PlanNode{input}}
+}
+
+func setOpName(op setOp) string {
+	if op.all {
+		return op.op + " ALL"
+	}
+	return op.op
+}
+
+func joinExprs(exprs []expr) string {
+	strs := make([]string, len(exprs))
+	for i, e := range exprs {
+		strs[i] = e.String()
+	}
+	return strings.Join(strs, ", ")
+}
+
+// insert returns the plan of an INSERT, which reads the sheet's header row,
+// together with the sheets of an INSERT ... SELECT, and appends every row in
+// one request.
+func (pl *planner) insert(stmt *insertStmt) *Plan {
+	q := stmt.query
+	detail := q.sheetName
+	if stmt.columns != nil {
+		columns := make([]string, len(stmt.columns))
+		for i, column := range stmt.columns {
+			columns[i] = quoteIdentifier(column)
+		}
+		detail += " (" + strings.Join(columns, ", ") + ")"
+	}
+
+	root := &PlanNode{Op: "Insert", Detail: detail}
+	ranges := []string{fmt.Sprintf("%s!1:1", q.sheetName)}
+	switch {
+	case stmt.source != nil:
+		root.Children = []*PlanNode{pl.query(stmt.source)}
+		ranges = append(ranges, stmt.source.sheetRanges()...)
+	case stmt.values != nil:
+		root.Children = []*PlanNode{{Op: "Values", Detail: fmt.Sprintf("%d rows", len(stmt.values))}}
+	default:
+		root.Detail += " from row struct"
+	}
+
+	return &Plan{
+		Root:     root,
+		Requests: []string{readRequest(ranges), fmt.Sprintf("AppendRows %s!A:Z", q.sheetName)},
+	}
+}
+
+// write returns the plan of an UPDATE or DELETE, which reads the sheet on
+// its own and then the sheets of subqueries in WHERE and in values as they
+// first run. Whether they run at all depends on the rows.
+func (pl *planner) write(op, detail string, q *Query, values ...expr) *Plan {
+	node := pl.table(q.sheetName, nil, "")
+	if q.where != nil {
+		node = pl.stage("Filter (client-side)", q.where.String(), node, q.where)
+	}
+	root := pl.stage(op, detail, node, values...)
+
+	plan := &Plan{Root: root, Requests: []string{fmt.Sprintf("ReadRange %s!A:Z", q.sheetName)}}
+	seen := make(map[string]bool)
+	for _, sub := range exprSubqueries(append([]expr{q.where}, values...)) {
+		var missing []string
+		for _, r := range sub.sheetRanges() {
+			if !seen[r] {
+				seen[r] = true
+				missing = append(missing, r)
+			}
+		}
+		if len(missing) > 0 {
+			plan.ConditionalRequests = append(plan.ConditionalRequests, readRequest(missing))
+		}
+	}
+
+	return plan
+}
@@ -0,0 +1,202 @@
+package sheetsql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSQLParser_Explain(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected string
+	}{
+		{
+			name: "join, group and sort",
+			sql:  "SELECT Name, SUM(Total) AS Spent FROM Users u JOIN Orders o ON o.UserID = u.ID WHERE Total > 1 GROUP BY Name ORDER BY Spent DESC LIMIT 2",
+			expected: `
+Project: Name, SUM(Total) AS Spent
+  Limit: LIMIT 2
+    Sort: Spent DESC
+      Aggregate: GROUP BY Name
+        Filter (client-side): Total > 1
+          Hash Join: INNER ON o.UserID = u.ID
+            Scan: Users!A:Z AS u
+            Scan: Orders!A:Z AS o
+API requests: 1
+  ReadRanges Users!A:Z, Orders!A:Z
+`,
+		},
+		{
+			name: "subquery and UNION",
+			sql:  "SELECT Name FROM Users WHERE ID IN (SELECT UserID FROM Orders) UNION SELECT Name FROM Teams ORDER BY Name",
+			expected: `
+Sort: Name
+  UNION
+    Project: Name
+      Filter (client-side): ID IN (SELECT UserID FROM Orders)
+        Scan: Users!A:Z
+        Subquery
+          Project: UserID
+            Scan: Orders!A:Z
+    Project: Name
+      Scan: Teams!A:Z
+API requests: 1
+  ReadRanges Users!A:Z, Orders!A:Z, Teams!A:Z
+`,
+		},
+		{
+			name: "recursive CTE read twice",
+			sql:  "WITH RECURSIVE r (N) AS (SELECT 1 FROM Users UNION SELECT N + 1 FROM r WHERE N < 3) SELECT N, ROW_NUMBER() OVER (ORDER BY N) FROM r, r AS s",
+			expected: `
+Project: N, ROW_NUMBER() OVER (ORDER BY N)
+  Window: ROW_NUMBER() OVER (ORDER BY N)
+    Cross Join
+      CTE Scan: r
+        Recursive UNION: until no new rows, at most 1000 rounds
+          Project: 1
+            Scan: Users!A:Z
+          Project: N + 1
+            Filter (client-side): N < 3
+              CTE Scan: r (previous round)
+      CTE Scan: r (reused) AS s
+API requests: 1
+  ReadRange Users!A:Z
+`,
+		},
+		{
+			name: "insert select",
+			sql:  "INSERT INTO Users (ID, Name) SELECT ID, Name FROM Teams",
+			expected: `
+Insert: Users (ID, Name)
+  Project: ID, Name
+    Scan: Teams!A:Z
+API requests: 2
+  ReadRanges Users!1:1, Teams!A:Z
+  AppendRows Users!A:Z
+`,
+		},
+		{
+			name: "update with subqueries",
+			sql:  "UPDATE Users SET Name = (SELECT MAX(ID) FROM Orders) WHERE ID IN (SELECT UserID FROM Orders)",
+			expected: `
+Update: Users SET Name = (SELECT MAX(ID) FROM Orders)
+  Filter (client-side): ID IN (SELECT UserID FROM Orders)
+    Scan: Users!A:Z
+    Subquery
+      Project: UserID
+        Scan: Orders!A:Z
+  Subquery
+    Project: MAX(ID)
+      Aggregate: all rows
+        Scan: Orders!A:Z
+API requests: 1, plus up to 1 if needed, plus 1 per changed row
+  ReadRange Users!A:Z
+  if needed: ReadRange Orders!A:Z
+  per changed row: UpdateRange Users!A<row>:Z<row>
+`,
+		},
+		{
+			name: "delete",
+			sql:  "DELETE FROM Users WHERE ID = 1",
+			expected: `
+Delete: Users
+  Filter (client-side): ID = 1
+    Scan: Users!A:Z
+API requests: 1, plus 2 per changed row
+  ReadRange Users!A:Z
+  per changed row: SheetProperties
+  per changed row: BatchUpdate (delete row)
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newJoinTestBackend()
+			plan, err := NewSQLParser(NewClientWithBackend("test-id", backend)).Explain(tt.sql)
+			if err != nil {
+				t.Fatalf("Explain() error = %v", err)
+			}
+			if got, expected := plan.String(), strings.TrimPrefix(tt.expected, "\n"); got != expected {
+				t.Errorf("Explain() =\n%s\nexpected\n%s", got, expected)
+			}
+			if backend.reads != 0 {
+				t.Errorf("Explain() made %d read requests, expected none", backend.reads)
+			}
+		})
+	}
+
+	if _, err := NewSQLParser(&Client{}).Explain("SELECT FROM"); err == nil || !strings.HasPrefix(err.Error(), "failed to parse SQL: ") {
+		t.Errorf("Explain() error = %v, expected a syntax error", err)
+	}
+}
+
+func TestSQLParser_Explain_ConditionalRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		matches bool
+	}{
+		{"subquery in SET, no match", "UPDATE Users SET Name = (SELECT MAX(ID) FROM Orders) WHERE ID > 10", false},
+		{"subquery in SET, match", "UPDATE Users SET Name = (SELECT MAX(ID) FROM Orders) WHERE ID = 1", true},
+		{"subquery after AND, no match", "UPDATE Users SET Name = 'x' WHERE ID > 10 AND TeamID IN (SELECT TeamID FROM Teams)", false},
+		{"subquery after AND, match", "UPDATE Users SET Name = 'x' WHERE ID = 1 AND TeamID IN (SELECT TeamID FROM Teams)", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newJoinTestBackend()
+			parser := NewSQLParser(NewClientWithBackend("test-id", backend))
+			plan, err := parser.Explain(tt.sql)
+			if err != nil {
+				t.Fatalf("Explain() error = %v", err)
+			}
+			stmt, err := parser.Prepare(tt.sql)
+			if err != nil {
+				t.Fatalf("Prepare() error = %v", err)
+			}
+			n, err := stmt.Exec()
+			if err != nil {
+				t.Fatalf("Exec() error = %v", err)
+			}
+			if (n > 0) != tt.matches {
+				t.Fatalf("Exec() changed %d rows", n)
+			}
+
+			expected := len(plan.Requests)
+			if tt.matches {
+				expected += len(plan.ConditionalRequests)
+			}
+			if backend.reads != expected {
+				t.Errorf("Exec() made %d read requests, expected %d from\n%s", backend.reads, expected, plan)
+			}
+		})
+	}
+}
+
+func TestQuery_Explain(t *testing.T) {
+	backend := newJoinTestBackend()
+	client := NewClientWithBackend("test-id", backend)
+
+	query := client.From("Orders").Join("Users", "UserID", "ID").Where("Total", ">", 5).OrderBy("Total", "DESC").Limit(1)
+	plan, err := query.Explain()
+	if err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+	if len(plan.Requests) != 1 || plan.Root.Op != "Project" {
+		t.Errorf("Explain() =\n%s", plan)
+	}
+
+	// The plan's requests are the ones the query makes.
+	if _, _, err := query.rows(); err != nil {
+		t.Fatalf("rows() error = %v", err)
+	}
+	if backend.reads != len(plan.Requests) {
+		t.Errorf("query made %d read requests, plan has %d", backend.reads, len(plan.Requests))
+	}
+
+	if _, err := client.From("Orders").OrderBy("Total", "UP").Explain(); err == nil {
+		t.Error("Explain() error = nil, expected the query's error")
+	}
+}
@@ -0,0 +1,606 @@
+package sheetsql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// expr is a node of an expression tree evaluated against one row. Boolean
+// expressions follow SQL's three-valued logic: they evaluate to true, false
+// or nil when the result is unknown because of a NULL operand.
+type expr interface {
+	eval(env *evalEnv) (interface{}, error)
+	String() string
+}
+
+// evalEnv is the row an expression is evaluated against. For grouped
+// queries row is the first row of the group and aggregates holds the values
+// of the group's aggregate functions. windows holds the row's values of
+// window functions.
+type evalEnv struct {
+	query      *Query
+	row        []interface{}
+	fieldMap   map[string]int
+	aggregates map[*aggregateExpr]interface{}
+	windows    map[*windowExpr]interface{}
+
+	// exec is the execution the row belongs to and outer the row of the
+	// enclosing query when evaluating inside a subquery.
+	exec  *execution
+	outer *evalEnv
+}
+
+// with returns a copy of env for another row.
+func (env *evalEnv) with(row []interface{}) *evalEnv {
+	return &evalEnv{query: env.query, row: row, fieldMap: env.fieldMap, exec: env.exec, outer: env.outer}
+}
+
+// columnRef is a column name used as a value, as opposed to a string literal.
+// table is set when the name is qualified, as in Users.Name.
+type columnRef struct {
+	table string
+	name  string
+}
+
+// key is the name the column is found under in a field map.
+func (c *columnRef) key() string {
+	if c.table != "" {
+		return c.table + "." + c.name
+	}
+	return c.name
+}
+
+func (c *columnRef) eval(env *evalEnv) (interface{}, error) {
+	colIndex, exists := env.fieldMap[c.key()]
+	if !exists {
+		return nil, fmt.Errorf("unknown column %q", c.key())
+	}
+	if colIndex == outerColumn {
+		env.exec.correlated[env.query] = true
+		return c.eval(env.outer)
+	}
+	if colIndex == ambiguousColumn {
+		return nil, fmt.Errorf("ambiguous column %q", c.key())
+	}
+
+	// Cells past the end of the row were trimmed by the API and are NULL.
+	if colIndex >= len(env.row) {
+		return nil, nil
+	}
+	return env.row[colIndex], nil
+}
+
+func (c *columnRef) String() string {
+	if c.table != "" {
+		return quoteIdentifier(c.table) + "." + quoteIdentifier(c.name)
+	}
+	return quoteIdentifier(c.name)
+}
+
+type literal struct {
+	value interface{}
+}
+
+func (l *literal) eval(env *evalEnv) (interface{}, error) {
+	return l.value, nil
+}
+
+func (l *literal) String() string {
+	return formatLiteral(l.value)
+}
+
+// comparison applies a WHERE operator to two operands.
+type comparison struct {
+	op    string
+	left  expr
+	right expr
+
+	// escape is the ESCAPE character of a LIKE or ILIKE pattern, or nil for
+	// the default.
+	escape expr
+}
+
+func (c *comparison) eval(env *evalEnv) (interface{}, error) {
+	left, err := c.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := c.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if left == nil || right == nil {
+		return nil, nil
+	}
+
+	if c.escape != nil {
+		escape, err := c.escape.eval(env)
+		if err != nil || escape == nil {
+			return nil, err
+		}
+		return env.matchPattern(c.op, formatValue(left), formatValue(right), formatValue(escape))
+	}
+
+	if result, ok, err := env.query.compareTemporal(left, c.op, right); ok {
+		return result, err
+	}
+	return env.applyOperator(left, c.op, right)
+}
+
+func (c *comparison) String() string {
+	if c.escape != nil {
+		return fmt.Sprintf("%s %s %s ESCAPE %s", c.left, c.op, c.right, c.escape)
+	}
+	return fmt.Sprintf("%s %s %s", c.left, c.op, c.right)
+}
+
+// logicalExpr joins two conditions with AND or OR.
+type logicalExpr struct {
+	op    string
+	left  expr
+	right expr
+}
+
+func (l *logicalExpr) eval(env *evalEnv) (interface{}, error) {
+	left, err := evalCondition(l.left, env)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit where the right-hand side cannot change the result.
+	if l.op == "AND" && left == false || l.op == "OR" && left == true {
+		return left, nil
+	}
+
+	right, err := evalCondition(l.right, env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case l.op == "AND" && right == false:
+		return false, nil
+	case l.op == "OR" && right == true:
+		return true, nil
+	case left == nil || right == nil:
+		return nil, nil
+	}
+	return right, nil
+}
+
+func (l *logicalExpr) String() string {
+	return fmt.Sprintf("%s %s %s", l.operandString(l.left), l.op, l.operandString(l.right))
+}
+
+// operandString parenthesises an OR nested inside an AND, the only case where
+// precedence would otherwise change the meaning.
+func (l *logicalExpr) operandString(e expr) string {
+	if inner, ok := e.(*logicalExpr); ok && l.op == "AND" && inner.op == "OR" {
+		return "(" + inner.String() + ")"
+	}
+	return e.String()
+}
+
+type notExpr struct {
+	operand expr
+}
+
+func (n *notExpr) eval(env *evalEnv) (interface{}, error) {
+	value, err := evalCondition(n.operand, env)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	return !value.(bool), nil
+}
+
+func (n *notExpr) String() string {
+	if _, ok := n.operand.(*logicalExpr); ok {
+		return "NOT (" + n.operand.String() + ")"
+	}
+	return "NOT " + n.operand.String()
+}
+
+// inExpr tests whether operand equals one of values, or one of the values
+// returned by a one-column subquery. As in SQL, the result is unknown rather
+// than false when there is no match but a NULL was among the values.
+type inExpr struct {
+	operand expr
+	values  []expr
+	query   *Query
+	not     bool
+}
+
+func (in *inExpr) eval(env *evalEnv) (interface{}, error) {
+	value, err := in.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []interface{}
+	if in.query != nil {
+		result, err := runSubquery(in.query, env)
+		if err != nil {
+			return nil, err
+		}
+		if len(result.columns) != 1 {
+			return nil, fmt.Errorf("subquery must return one column, not %d", len(result.columns))
+		}
+		for _, row := range result.rows {
+			candidates = append(candidates, row[0])
+		}
+	} else {
+		for _, e := range in.values {
+			candidate, err := e.eval(env)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	var sawNull bool
+	for _, candidate := range candidates {
+		if candidate == nil {
+			sawNull = true
+			continue
+		}
+		equal, ok, err := env.query.compareTemporal(value, "=", candidate)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			equal = formatValue(value) == formatValue(candidate)
+		}
+		if equal == true {
+			return !in.not, nil
+		}
+	}
+
+	if sawNull {
+		return nil, nil
+	}
+	return in.not, nil
+}
+
+func (in *inExpr) String() string {
+	op := " IN ("
+	if in.not {
+		op = " NOT IN ("
+	}
+
+	if in.query != nil {
+		return in.operand.String() + op + in.query.String() + ")"
+	}
+
+	values := make([]string, len(in.values))
+	for i, value := range in.values {
+		values[i] = value.String()
+	}
+	return in.operand.String() + op + strings.Join(values, ", ") + ")"
+}
+
+// betweenExpr tests whether operand lies in the inclusive range [low, high],
+// comparing values the same way as <= and >=.
+type betweenExpr struct {
+	operand expr
+	low     expr
+	high    expr
+	not     bool
+}
+
+func (b *betweenExpr) eval(env *evalEnv) (interface{}, error) {
+	above, err := (&comparison{op: ">=", left: b.operand, right: b.low}).eval(env)
+	if err != nil {
+		return nil, err
+	}
+	below, err := (&comparison{op: "<=", left: b.operand, right: b.high}).eval(env)
+	if err != nil || above == nil || below == nil {
+		return nil, err
+	}
+
+	within := above == true && below == true
+	return within != b.not, nil
+}
+
+func (b *betweenExpr) String() string {
+	op := " BETWEEN "
+	if b.not {
+		op = " NOT BETWEEN "
+	}
+	return b.operand.String() + op + b.low.String() + " AND " + b.high.String()
+}
+
+// isNullExpr tests for NULL, which is a cell missing from the end of a row.
+// A cell that exists but is empty is not NULL.
+type isNullExpr struct {
+	operand expr
+	not     bool
+}
+
+func (n *isNullExpr) eval(env *evalEnv) (interface{}, error) {
+	value, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return (value == nil) != n.not, nil
+}
+
+func (n *isNullExpr) String() string {
+	if n.not {
+		return n.operand.String() + " IS NOT NULL"
+	}
+	return n.operand.String() + " IS NULL"
+}
+
+// evalCondition evaluates e and checks that it is a boolean or unknown (nil).
+func evalCondition(e expr, env *evalEnv) (interface{}, error) {
+	value, err := e.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch value.(type) {
+	case nil, bool:
+		return value, nil
+	}
+	return nil, fmt.Errorf("condition %s is not a boolean", e)
+}
+
+// eval applies the clause to the row. A clause naming a column the sheet
+// does not have, or a cell beyond the end of the row, does not filter.
+func (w *WhereClause) eval(env *evalEnv) (interface{}, error) {
+	colIndex, exists := env.fieldMap[w.Column]
+	if !exists {
+		return true, nil
+	}
+	if colIndex == outerColumn {
+		env.exec.correlated[env.query] = true
+		return w.eval(env.outer)
+	}
+	if colIndex == ambiguousColumn {
+		return nil, fmt.Errorf("ambiguous column %q", w.Column)
+	}
+
+	if colIndex >= len(env.row) {
+		return true, nil
+	}
+
+	if result, ok, err := env.query.compareTemporal(env.row[colIndex], w.Operator, w.Value); ok {
+		return result, err
+	}
+
+	return env.applyOperator(env.row[colIndex], w.Operator, w.Value)
+}
+
+func (w *WhereClause) String() string {
+	return fmt.Sprintf("%s %s %s", quoteIdentifier(w.Column), w.Operator, formatLiteral(w.Value))
+}
+
+// and combines two conditions, either of which may be nil.
+func and(left, right expr) expr {
+	return combine("AND", left, right)
+}
+
+func or(left, right expr) expr {
+	return combine("OR", left, right)
+}
+
+func combine(op string, left, right expr) expr {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+	return &logicalExpr{op: op, left: left, right: right}
+}
+
+// quoteIdentifier returns name as it would be written in SQL, quoting it with
+// backticks unless it is a plain, unreserved identifier.
+func quoteIdentifier(name string) string {
+	plain := name != "" && !reservedWords[strings.ToUpper(name)]
+	for i, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			plain = false
+			break
+		}
+	}
+
+	if plain {
+		return name
+	}
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func formatLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		if isDate(v) {
+			return "DATE '" + formatValue(v) + "'"
+		}
+		return "TIMESTAMP '" + formatValue(v) + "'"
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// formatValue returns the text of a cell or computed value. Times are written
+// as ISO 8601 dates, with the time of day unless it is midnight, so that they
+// sort correctly as text.
+func formatValue(value interface{}) string {
+	if t, ok := value.(time.Time); ok {
+		if isDate(t) {
+			return t.Format(dateLayout)
+		}
+		return t.Format(timestampLayout)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// walkExpr calls fn for e and every expression nested in it, skipping the
+// children of any expression for which fn returns false.
+func walkExpr(e expr, fn func(expr) bool) {
+	if e == nil || !fn(e) {
+		return
+	}
+
+	switch e := e.(type) {
+	case *comparison:
+		walkExpr(e.left, fn)
+		walkExpr(e.right, fn)
+		walkExpr(e.escape, fn)
+	case *logicalExpr:
+		walkExpr(e.left, fn)
+		walkExpr(e.right, fn)
+	case *notExpr:
+		walkExpr(e.operand, fn)
+	case *aggregateExpr:
+		walkExpr(e.arg, fn)
+	case *inExpr:
+		walkExpr(e.operand, fn)
+		for _, value := range e.values {
+			walkExpr(value, fn)
+		}
+	case *betweenExpr:
+		walkExpr(e.operand, fn)
+		walkExpr(e.low, fn)
+		walkExpr(e.high, fn)
+	case *isNullExpr:
+		walkExpr(e.operand, fn)
+	case *funcCall:
+		for _, arg := range e.args {
+			walkExpr(arg, fn)
+		}
+	case *binaryExpr:
+		walkExpr(e.left, fn)
+		walkExpr(e.right, fn)
+	case *negExpr:
+		walkExpr(e.operand, fn)
+	case *caseExpr:
+		walkExpr(e.operand, fn)
+		for _, w := range e.whens {
+			walkExpr(w.when, fn)
+			walkExpr(w.then, fn)
+		}
+		walkExpr(e.els, fn)
+	case *castExpr:
+		walkExpr(e.operand, fn)
+	case *extractExpr:
+		walkExpr(e.operand, fn)
+	case *windowExpr:
+		// The aggregate of a window is not one of the query's own, so
+		// only its argument is walked.
+		if e.agg != nil {
+			walkExpr(e.agg.arg, fn)
+		}
+		for _, arg := range e.args {
+			walkExpr(arg, fn)
+		}
+		for _, p := range e.partitionBy {
+			walkExpr(p, fn)
+		}
+		for _, item := range e.orderBy {
+			walkExpr(item.expr, fn)
+		}
+	}
+}
+
+// mapExpr returns a copy of e with every expression replaced by fn, applied
+// bottom-up. Subtrees fn leaves unchanged are shared, not copied.
+func mapExpr(e expr, fn func(expr) expr) expr {
+	switch e := e.(type) {
+	case nil:
+		return nil
+	case *comparison:
+		left, right, escape := mapExpr(e.left, fn), mapExpr(e.right, fn), mapExpr(e.escape, fn)
+		if left != e.left || right != e.right || escape != e.escape {
+			return fn(&comparison{op: e.op, left: left, right: right, escape: escape})
+		}
+	case *logicalExpr:
+		left, right := mapExpr(e.left, fn), mapExpr(e.right, fn)
+		if left != e.left || right != e.right {
+			return fn(&logicalExpr{op: e.op, left: left, right: right})
+		}
+	case *notExpr:
+		if operand := mapExpr(e.operand, fn); operand != e.operand {
+			return fn(&notExpr{operand: operand})
+		}
+	case *inExpr:
+		changed := &inExpr{operand: mapExpr(e.operand, fn), query: e.query, not: e.not}
+		same := changed.operand == e.operand
+		for _, value := range e.values {
+			mapped := mapExpr(value, fn)
+			same = same && mapped == value
+			changed.values = append(changed.values, mapped)
+		}
+		if !same {
+			return fn(changed)
+		}
+	case *betweenExpr:
+		operand, low, high := mapExpr(e.operand, fn), mapExpr(e.low, fn), mapExpr(e.high, fn)
+		if operand != e.operand || low != e.low || high != e.high {
+			return fn(&betweenExpr{operand: operand, low: low, high: high, not: e.not})
+		}
+	case *isNullExpr:
+		if operand := mapExpr(e.operand, fn); operand != e.operand {
+			return fn(&isNullExpr{operand: operand, not: e.not})
+		}
+	case *funcCall:
+		changed := &funcCall{name: e.name, fn: e.fn}
+		same := true
+		for _, arg := range e.args {
+			mapped := mapExpr(arg, fn)
+			same = same && mapped == arg
+			changed.args = append(changed.args, mapped)
+		}
+		if !same {
+			return fn(changed)
+		}
+	case *binaryExpr:
+		left, right := mapExpr(e.left, fn), mapExpr(e.right, fn)
+		if left != e.left || right != e.right {
+			return fn(&binaryExpr{op: e.op, left: left, right: right})
+		}
+	case *negExpr:
+		if operand := mapExpr(e.operand, fn); operand != e.operand {
+			return fn(&negExpr{operand: operand})
+		}
+	case *caseExpr:
+		changed := &caseExpr{operand: mapExpr(e.operand, fn), els: mapExpr(e.els, fn)}
+		same := changed.operand == e.operand && changed.els == e.els
+		for _, w := range e.whens {
+			mapped := whenClause{when: mapExpr(w.when, fn), then: mapExpr(w.then, fn)}
+			same = same && mapped == w
+			changed.whens = append(changed.whens, mapped)
+		}
+		if !same {
+			return fn(changed)
+		}
+	case *castExpr:
+		if operand := mapExpr(e.operand, fn); operand != e.operand {
+			return fn(&castExpr{operand: operand, typ: e.typ})
+		}
+	case *extractExpr:
+		if operand := mapExpr(e.operand, fn); operand != e.operand {
+			return fn(&extractExpr{unit: e.unit, operand: operand})
+		}
+	}
+	return fn(e)
+}
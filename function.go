@@ -0,0 +1,528 @@
+package sheetsql
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// scalarFunction is a function that computes one value per row, such as
+// UPPER(Name).
+type scalarFunction struct {
+	minArgs int
+	maxArgs int // -1 for any number
+
+	// call computes the result. Unless nullArgs is set it is not called
+	// when an argument is NULL, and the result is NULL.
+	call     func(env *evalEnv, args []interface{}) (interface{}, error)
+	nullArgs bool
+
+	// lazy, if set, replaces call for functions that must not evaluate every
+	// argument, so that IF(n = 0, 0, 1 / n) does not divide by zero.
+	lazy func(args []expr, env *evalEnv) (interface{}, error)
+}
+
+var scalarFunctions map[string]*scalarFunction
+
+func init() {
+	scalarFunctions = map[string]*scalarFunction{
+		"UPPER":   {minArgs: 1, maxArgs: 1, call: stringFunction(strings.ToUpper)},
+		"LOWER":   {minArgs: 1, maxArgs: 1, call: stringFunction(strings.ToLower)},
+		"TRIM":    {minArgs: 1, maxArgs: 1, call: stringFunction(strings.TrimSpace)},
+		"LENGTH":  {minArgs: 1, maxArgs: 1, call: length},
+		"SUBSTR":  {minArgs: 2, maxArgs: 3, call: substr},
+		"CONCAT":  {minArgs: 1, maxArgs: -1, call: concat, nullArgs: true},
+		"REPLACE": {minArgs: 3, maxArgs: 3, call: replace},
+		"ABS":     {minArgs: 1, maxArgs: 1, call: abs},
+		"ROUND":   {minArgs: 1, maxArgs: 2, call: round},
+		"FLOOR":   {minArgs: 1, maxArgs: 1, call: floatFunction(math.Floor)},
+		"CEIL":    {minArgs: 1, maxArgs: 1, call: floatFunction(math.Ceil)},
+		"NULLIF":  {minArgs: 2, maxArgs: 2, call: nullIf, nullArgs: true},
+
+		"COALESCE": {minArgs: 1, maxArgs: -1, lazy: coalesce},
+		"IF":       {minArgs: 3, maxArgs: 3, lazy: ifFunction},
+
+		"NOW":        {minArgs: 0, maxArgs: 0, call: nowFunction},
+		"DATE":       {minArgs: 1, maxArgs: 1, call: dateFunction},
+		"DATE_TRUNC": {minArgs: 2, maxArgs: 2, call: dateTrunc},
+		"DATE_ADD":   {minArgs: 3, maxArgs: 3, call: dateAdd},
+	}
+	scalarFunctions["CEILING"] = scalarFunctions["CEIL"]
+	scalarFunctions["SUBSTRING"] = scalarFunctions["SUBSTR"]
+}
+
+// funcCall is a call of a scalar function.
+type funcCall struct {
+	name string
+	args []expr
+	fn   *scalarFunction
+}
+
+func (f *funcCall) eval(env *evalEnv) (interface{}, error) {
+	if f.fn.lazy != nil {
+		return f.fn.lazy(f.args, env)
+	}
+
+	args := make([]interface{}, len(f.args))
+	for i, arg := range f.args {
+		value, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil && !f.fn.nullArgs {
+			return nil, nil
+		}
+		args[i] = value
+	}
+
+	result, err := f.fn.call(env, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.name, err)
+	}
+	return result, nil
+}
+
+func (f *funcCall) String() string {
+	args := make([]string, len(f.args))
+	for i, arg := range f.args {
+		args[i] = arg.String()
+	}
+	return f.name + "(" + strings.Join(args, ", ") + ")"
+}
+
+func stringFunction(fn func(string) string) func(*evalEnv, []interface{}) (interface{}, error) {
+	return func(env *evalEnv, args []interface{}) (interface{}, error) {
+		return fn(formatValue(args[0])), nil
+	}
+}
+
+func length(env *evalEnv, args []interface{}) (interface{}, error) {
+	return utf8.RuneCountInString(formatValue(args[0])), nil
+}
+
+// substr returns the characters of a string from a 1-based start position,
+// optionally limited to a length. As in PostgreSQL, a start before the first
+// character shortens the result rather than moving it.
+func substr(env *evalEnv, args []interface{}) (interface{}, error) {
+	runes := []rune(formatValue(args[0]))
+	start, err := intArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	end := int64(len(runes)) + 1
+	if len(args) == 3 {
+		n, err := intArg(args[2])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("negative substring length %d", n)
+		}
+		end = min(end, start+n)
+	}
+
+	start = max(start, 1)
+	if start >= end {
+		return "", nil
+	}
+	return string(runes[start-1 : end-1]), nil
+}
+
+func concat(env *evalEnv, args []interface{}) (interface{}, error) {
+	var b strings.Builder
+	for _, arg := range args {
+		if arg != nil {
+			b.WriteString(formatValue(arg))
+		}
+	}
+	return b.String(), nil
+}
+
+func replace(env *evalEnv, args []interface{}) (interface{}, error) {
+	return strings.ReplaceAll(formatValue(args[0]), formatValue(args[1]), formatValue(args[2])), nil
+}
+
+func abs(env *evalEnv, args []interface{}) (interface{}, error) {
+	n, err := numberArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if i, ok := n.(int64); ok {
+		if i < 0 {
+			return -i, nil
+		}
+		return i, nil
+	}
+	return math.Abs(n.(float64)), nil
+}
+
+// round rounds half away from zero, to a whole number or to the given number
+// of decimal places.
+func round(env *evalEnv, args []interface{}) (interface{}, error) {
+	n, err := numberArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	places := int64(0)
+	if len(args) == 2 {
+		if places, err = intArg(args[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := n.(int64); ok && places >= 0 {
+		return n, nil
+	}
+	f := toFloat(n)
+	if len(args) == 1 {
+		return int64(math.Round(f)), nil
+	}
+	scale := math.Pow(10, float64(places))
+	return math.Round(f*scale) / scale, nil
+}
+
+// floatFunction applies fn to a number, returning an integer.
+func floatFunction(fn func(float64) float64) func(*evalEnv, []interface{}) (interface{}, error) {
+	return func(env *evalEnv, args []interface{}) (interface{}, error) {
+		n, err := numberArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if f, ok := n.(float64); ok {
+			return int64(fn(f)), nil
+		}
+		return n, nil
+	}
+}
+
+func nullIf(env *evalEnv, args []interface{}) (interface{}, error) {
+	if args[0] != nil && args[1] != nil && formatValue(args[0]) == formatValue(args[1]) {
+		return nil, nil
+	}
+	return args[0], nil
+}
+
+func coalesce(args []expr, env *evalEnv) (interface{}, error) {
+	for _, arg := range args {
+		value, err := arg.eval(env)
+		if err != nil || value != nil {
+			return value, err
+		}
+	}
+	return nil, nil
+}
+
+func ifFunction(args []expr, env *evalEnv) (interface{}, error) {
+	cond, err := evalCondition(args[0], env)
+	if err != nil {
+		return nil, err
+	}
+	if cond == true {
+		return args[1].eval(env)
+	}
+	return args[2].eval(env)
+}
+
+// numberArg converts a value to an int64 or a float64. Cells hold numbers as
+// strings, so strings are parsed.
+func numberArg(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case float64:
+		return v, nil
+	}
+
+	s := strings.TrimSpace(formatValue(v))
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("%q is not a number", formatValue(v))
+}
+
+// intArg converts a value to an integer, truncating any fraction.
+func intArg(v interface{}) (int64, error) {
+	n, err := numberArg(v)
+	if f, ok := n.(float64); ok {
+		return int64(f), nil
+	}
+	i, _ := n.(int64)
+	return i, err
+}
+
+// binaryExpr is an arithmetic operation, +, -, *, / or %, or the string
+// concatenation ||.
+type binaryExpr struct {
+	op    string
+	left  expr
+	right expr
+}
+
+func (b *binaryExpr) eval(env *evalEnv) (interface{}, error) {
+	left, err := b.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := b.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.op == "||" {
+		if left == nil || right == nil {
+			return nil, nil
+		}
+		return formatValue(left) + formatValue(right), nil
+	}
+
+	// Empty cells are NULL in arithmetic, as in aggregate functions.
+	if isBlank(left) || isBlank(right) {
+		return nil, nil
+	}
+
+	x, err := numberArg(left)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b, err)
+	}
+	y, err := numberArg(right)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b, err)
+	}
+
+	result, err := arithmetic(b.op, x, y)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b, err)
+	}
+	return result, nil
+}
+
+// arithmetic applies op to two numbers. Integers stay integers, except that
+// division that does not come out even gives a float.
+func arithmetic(op string, x, y interface{}) (interface{}, error) {
+	a, aInt := x.(int64)
+	b, bInt := y.(int64)
+	if aInt && bInt {
+		switch op {
+		case "+":
+			return a + b, nil
+		case "-":
+			return a - b, nil
+		case "*":
+			return a * b, nil
+		case "/", "%":
+			if b == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if op == "%" {
+				return a % b, nil
+			}
+			if a%b == 0 {
+				return a / b, nil
+			}
+		}
+	}
+
+	f, g := toFloat(x), toFloat(y)
+	switch op {
+	case "+":
+		return f + g, nil
+	case "-":
+		return f - g, nil
+	case "*":
+		return f * g, nil
+	case "/", "%":
+		if g == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if op == "%" {
+			return math.Mod(f, g), nil
+		}
+		return f / g, nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", op)
+}
+
+// precedence returns how tightly an arithmetic operator binds.
+func precedence(op string) int {
+	switch op {
+	case "*", "/", "%":
+		return 2
+	}
+	return 1
+}
+
+func (b *binaryExpr) String() string {
+	return b.operandString(b.left, false) + " " + b.op + " " + b.operandString(b.right, true)
+}
+
+// operandString parenthesises an operand where its operator binds less
+// tightly than b's, or as tightly on the right, since the operators are
+// left-associative.
+func (b *binaryExpr) operandString(e expr, right bool) string {
+	switch e := e.(type) {
+	case *binaryExpr:
+		p, q := precedence(e.op), precedence(b.op)
+		if p < q || right && p == q {
+			return "(" + e.String() + ")"
+		}
+		return e.String()
+	case *columnRef, *literal, *funcCall, *aggregateExpr, *caseExpr, *castExpr, *extractExpr, *negExpr, *subqueryExpr, *windowExpr:
+		return e.String()
+	}
+	return "(" + e.String() + ")"
+}
+
+// negExpr is a unary minus applied to anything but a number literal.
+type negExpr struct {
+	operand expr
+}
+
+func (n *negExpr) eval(env *evalEnv) (interface{}, error) {
+	value, err := n.operand.eval(env)
+	if err != nil || isBlank(value) {
+		return nil, err
+	}
+
+	x, err := numberArg(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n, err)
+	}
+	return arithmetic("-", int64(0), x)
+}
+
+func (n *negExpr) String() string {
+	switch n.operand.(type) {
+	case *columnRef, *funcCall, *aggregateExpr, *castExpr, *extractExpr:
+		return "-" + n.operand.String()
+	}
+	return "-(" + n.operand.String() + ")"
+}
+
+// caseExpr is a CASE expression. With an operand it is a simple CASE, whose
+// WHEN values are compared with the operand; without one each WHEN is a
+// condition.
+type caseExpr struct {
+	operand expr
+	whens   []whenClause
+	els     expr
+}
+
+type whenClause struct {
+	when expr
+	then expr
+}
+
+func (c *caseExpr) eval(env *evalEnv) (interface{}, error) {
+	var operand interface{}
+	if c.operand != nil {
+		value, err := c.operand.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		operand = value
+	}
+
+	for _, w := range c.whens {
+		var matched bool
+		if c.operand != nil {
+			value, err := w.when.eval(env)
+			if err != nil {
+				return nil, err
+			}
+			matched = operand != nil && value != nil && formatValue(operand) == formatValue(value)
+		} else {
+			cond, err := evalCondition(w.when, env)
+			if err != nil {
+				return nil, err
+			}
+			matched = cond == true
+		}
+
+		if matched {
+			return w.then.eval(env)
+		}
+	}
+
+	if c.els != nil {
+		return c.els.eval(env)
+	}
+	return nil, nil
+}
+
+func (c *caseExpr) String() string {
+	str := "CASE"
+	if c.operand != nil {
+		str += " " + c.operand.String()
+	}
+	for _, w := range c.whens {
+		str += " WHEN " + w.when.String() + " THEN " + w.then.String()
+	}
+	if c.els != nil {
+		str += " ELSE " + c.els.String()
+	}
+	return str + " END"
+}
+
+// castTypes maps the type names CAST accepts to the type they convert to.
+var castTypes = map[string]string{
+	"INT": "INT", "INTEGER": "INT", "FLOAT": "FLOAT", "REAL": "FLOAT",
+	"TEXT": "TEXT", "VARCHAR": "TEXT", "DATE": "DATE", "TIMESTAMP": "TIMESTAMP",
+}
+
+// castExpr converts a value with CAST(x AS type).
+type castExpr struct {
+	operand expr
+	typ     string
+}
+
+func (c *castExpr) eval(env *evalEnv) (interface{}, error) {
+	value, err := c.operand.eval(env)
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	s := strings.TrimSpace(formatValue(value))
+	switch c.typ {
+	case "INT":
+		if s == "" {
+			return nil, nil
+		}
+		if n, err := intArg(value); err == nil {
+			return n, nil
+		}
+	case "FLOAT":
+		if s == "" {
+			return nil, nil
+		}
+		if n, err := numberArg(value); err == nil {
+			return toFloat(n), nil
+		}
+	case "TEXT":
+		return formatValue(value), nil
+	case "DATE", "TIMESTAMP":
+		if s == "" {
+			return nil, nil
+		}
+		if t, err := env.query.toTime(value); err == nil {
+			if c.typ == "DATE" {
+				t = t.Truncate(24 * time.Hour)
+			}
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot cast %q to %s", s, c.typ)
+}
+
+func (c *castExpr) String() string {
+	return "CAST(" + c.operand.String() + " AS " + c.typ + ")"
+}
@@ -0,0 +1,133 @@
+package sheetsql
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSQLParser_Query_Functions(t *testing.T) {
+	mock := NewMockSheetsService()
+	mock.AddSheetData("Items", [][]interface{}{
+		{"SKU", "Name", "Price", "Qty", "Added"},
+		{"a-1", "  Widget ", "2.5", "4", "2024-03-01"},
+		{"b-2", "Gadget", "10", "3", "3/15/2024"},
+		{"c-3", "gizmo", "-7", ""},
+	})
+	parser := NewSQLParser(mock.Client("test-id"))
+
+	tests := []struct {
+		name     string
+		sql      string
+		columns  []string
+		expected [][]interface{}
+		wantErr  string
+	}{
+		{
+			name:     "string functions",
+			sql:      "SELECT UPPER(SKU), LOWER(Name) AS lower, TRIM(Name) AS trimmed, LENGTH(Name) AS len FROM Items LIMIT 1",
+			columns:  []string{"UPPER(SKU)", "lower", "trimmed", "len"},
+			expected: [][]interface{}{{"A-1", "  widget ", "Widget", 9}},
+		},
+		{
+			name:     "substr, concat and replace",
+			sql:      "SELECT SUBSTR(SKU, 1, 1) AS a, SUBSTR(Name, 3) AS b, SUBSTR(SKU, 0, 2) AS c, CONCAT(SKU, ':', Qty) AS d, REPLACE(SKU, '-', '') AS e, SKU || '/' || Qty AS f FROM Items WHERE SKU = 'c-3'",
+			columns:  []string{"a", "b", "c", "d", "e", "f"},
+			expected: [][]interface{}{{"c", "zmo", "c", "c-3:", "c3", nil}},
+		},
+		{
+			name:     "arithmetic",
+			sql:      "SELECT Price * Qty AS total, Qty + 1 AS more, Qty / 2 AS half, Qty % 2 AS odd, -Price AS neg FROM Items WHERE Qty <> ''",
+			columns:  []string{"total", "more", "half", "odd", "neg"},
+			expected: [][]interface{}{{10.0, int64(5), int64(2), int64(0), -2.5}, {int64(30), int64(4), 1.5, int64(1), int64(-10)}},
+		},
+		{
+			name:     "precedence",
+			sql:      "SELECT 1 + 2 * 3 AS a, (1 + 2) * 3 AS b, 10 - 4 - 3 AS c FROM Items LIMIT 1",
+			columns:  []string{"a", "b", "c"},
+			expected: [][]interface{}{{int64(7), int64(9), int64(3)}},
+		},
+		{
+			name:     "numeric functions",
+			sql:      "SELECT ABS(Price) AS a, ROUND(Price) AS r, ROUND(Price / 3, 2) AS r2, FLOOR(Price) AS f, CEIL(Price) AS c FROM Items",
+			columns:  []string{"a", "r", "r2", "f", "c"},
+			expected: [][]interface{}{{2.5, int64(3), 0.83, int64(2), int64(3)}, {int64(10), int64(10), 3.33, int64(10), int64(10)}, {int64(7), int64(-7), -2.33, int64(-7), int64(-7)}},
+		},
+		{
+			name:     "empty cells are null",
+			sql:      "SELECT Qty * 2 AS a, COALESCE(Added, 'never') AS b, NULLIF(Qty, '') AS c FROM Items WHERE SKU = 'c-3'",
+			columns:  []string{"a", "b", "c"},
+			expected: [][]interface{}{{nil, "never", nil}},
+		},
+		{
+			name:     "conditionals",
+			sql:      "SELECT SKU, CASE WHEN Price > 5 THEN 'high' WHEN Price > 0 THEN 'low' ELSE 'refund' END AS band, CASE Qty WHEN 3 THEN 'three' END AS three, IF(Qty = 0, 0, Price / Qty) AS unit FROM Items",
+			columns:  []string{"SKU", "band", "three", "unit"},
+			expected: [][]interface{}{{"a-1", "low", nil, 0.625}, {"b-2", "high", "three", 3.3333333333333335}, {"c-3", "refund", nil, nil}},
+		},
+		{
+			name:     "cast",
+			sql:      "SELECT CAST(Price AS INT) AS i, CAST(Qty AS FLOAT) AS f, CAST(Price AS TEXT) AS t, CAST(Added AS DATE) AS d FROM Items WHERE Added IS NOT NULL",
+			columns:  []string{"i", "f", "t", "d"},
+			expected: [][]interface{}{{int64(2), 4.0, "2.5", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}, {int64(10), 3.0, "10", time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)}},
+		},
+		{
+			name:     "functions in where and order by",
+			sql:      "SELECT SKU FROM Items WHERE LOWER(TRIM(Name)) LIKE 'g%' ORDER BY LENGTH(Name) DESC, Price * -1",
+			columns:  []string{"SKU"},
+			expected: [][]interface{}{{"b-2"}, {"c-3"}},
+		},
+		{
+			name:     "functions over aggregates",
+			sql:      "SELECT ROUND(SUM(Price * Qty) / COUNT(*), 1) AS avg FROM Items",
+			columns:  []string{"avg"},
+			expected: [][]interface{}{{13.3}},
+		},
+		{
+			name:    "not a number",
+			sql:     "SELECT Name + 1 FROM Items",
+			wantErr: `Name + 1: "  Widget " is not a number`,
+		},
+		{
+			name:    "division by zero",
+			sql:     "SELECT Qty / (Qty - Qty) FROM Items",
+			wantErr: "Qty / (Qty - Qty): division by zero",
+		},
+		{
+			name:    "bad cast",
+			sql:     "SELECT CAST(Name AS INT) FROM Items",
+			wantErr: `cannot cast "Widget" to INT`,
+		},
+		{
+			name:    "bad argument",
+			sql:     "SELECT SUBSTR(Name, 'x') FROM Items",
+			wantErr: `SUBSTR: "x" is not a number`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := parser.parseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("parseSQL() error = %v", err)
+			}
+
+			columns, rows, err := query.rows()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("rows() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rows() error = %v", err)
+			}
+			if !reflect.DeepEqual(columns, tt.columns) {
+				t.Errorf("columns = %v, expected %v", columns, tt.columns)
+			}
+			if !reflect.DeepEqual(rows, tt.expected) {
+				t.Errorf("rows = %#v, expected %#v", rows, tt.expected)
+			}
+		})
+	}
+}
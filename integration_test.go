@@ -18,13 +18,24 @@ type User struct {
 	City  string `sheet:"City"`
 }
 
+// setupIntegrationTest connects to the real test spreadsheet when
+// GOOGLE_CREDENTIALS_FILE is set and to a local emulator otherwise.
 func setupIntegrationTest(t *testing.T) *Client {
+	ctx := context.Background()
+
 	credentialsFile := os.Getenv("GOOGLE_CREDENTIALS_FILE")
 	if credentialsFile == "" {
-		t.Skip("GOOGLE_CREDENTIALS_FILE environment variable not set, skipping integration tests")
+		emu := NewEmulator()
+		t.Cleanup(emu.Close)
+		emu.SetSheet("Sheet1", SetupTestData().GetSheetData("Users"))
+
+		client, err := NewClient(ctx, testSpreadsheetID, emu.ClientOptions()...)
+		if err != nil {
+			t.Fatalf("Failed to create emulator client: %v", err)
+		}
+		return client
 	}
 
-	ctx := context.Background()
 	client, err := NewClient(ctx, testSpreadsheetID, option.WithCredentialsFile(credentialsFile))
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
@@ -33,6 +44,17 @@ func setupIntegrationTest(t *testing.T) *Client {
 	return client
 }
 
+// setupWriteTest is setupIntegrationTest for tests that modify the sheet.
+// Writes always run against the emulator but need ENABLE_WRITE_TESTS=true
+// against the real spreadsheet.
+func setupWriteTest(t *testing.T) *Client {
+	if os.Getenv("GOOGLE_CREDENTIALS_FILE") != "" && os.Getenv("ENABLE_WRITE_TESTS") != "true" {
+		t.Skip("Write tests disabled. Set ENABLE_WRITE_TESTS=true to enable")
+	}
+
+	return setupIntegrationTest(t)
+}
+
 func TestIntegration_BasicQuery(t *testing.T) {
 	client := setupIntegrationTest(t)
 
@@ -186,13 +208,40 @@ func TestIntegration_SQLComplexQuery(t *testing.T) {
 	t.Logf("Complex SQL query found %d users", len(users))
 }
 
-func TestIntegration_Insert(t *testing.T) {
+func TestIntegration_OrderBy(t *testing.T) {
 	client := setupIntegrationTest(t)
+	parser := NewSQLParser(client)
 
-	if os.Getenv("ENABLE_WRITE_TESTS") != "true" {
-		t.Skip("Write tests disabled. Set ENABLE_WRITE_TESTS=true to enable")
+	var users []User
+	err := parser.Query("SELECT * FROM Sheet1 ORDER BY Age DESC LIMIT 3", &users)
+	if err != nil {
+		t.Fatalf("Failed to execute ORDER BY query: %v", err)
+	}
+
+	if len(users) > 3 {
+		t.Errorf("Expected at most 3 users, got %d", len(users))
 	}
 
+	for i := 1; i < len(users); i++ {
+		if users[i].Age > users[i-1].Age {
+			t.Errorf("User %s (age %d) sorted after %s (age %d)", users[i].Name, users[i].Age, users[i-1].Name, users[i-1].Age)
+		}
+	}
+
+	var all []User
+	if err := client.From("Sheet1").Get(&all); err != nil {
+		t.Fatalf("Failed to query all users: %v", err)
+	}
+	for _, user := range all {
+		if len(users) > 0 && user.Age > users[0].Age {
+			t.Errorf("User %s (age %d) is older than the first result %s", user.Name, user.Age, users[0].Name)
+		}
+	}
+}
+
+func TestIntegration_Insert(t *testing.T) {
+	client := setupWriteTest(t)
+
 	newUser := User{
 		Name:  "Test User",
 		Email: "test@example.com",
@@ -224,11 +273,7 @@ func TestIntegration_Insert(t *testing.T) {
 }
 
 func TestIntegration_Update(t *testing.T) {
-	client := setupIntegrationTest(t)
-
-	if os.Getenv("ENABLE_WRITE_TESTS") != "true" {
-		t.Skip("Write tests disabled. Set ENABLE_WRITE_TESTS=true to enable")
-	}
+	client := setupWriteTest(t)
 
 	testUser := User{
 		Name:  "Update Test User",
@@ -300,11 +345,7 @@ func TestIntegration_Update(t *testing.T) {
 }
 
 func TestIntegration_Delete(t *testing.T) {
-	client := setupIntegrationTest(t)
-
-	if os.Getenv("ENABLE_WRITE_TESTS") != "true" {
-		t.Skip("Write tests disabled. Set ENABLE_WRITE_TESTS=true to enable")
-	}
+	client := setupWriteTest(t)
 
 	testUser := User{
 		Name:  "Delete Test User",
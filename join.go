@@ -0,0 +1,336 @@
+package sheetsql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// joinClause joins another sheet to a query. on is nil for a CROSS JOIN.
+type joinClause struct {
+	kind  string // INNER, LEFT or CROSS
+	sheet string
+	cte   *cte // read instead of the sheet, if set
+	alias string
+	on    expr
+}
+
+func (j joinClause) String() string {
+	str := "JOIN " + quoteIdentifier(j.sheet)
+	if j.kind != "INNER" {
+		str = j.kind + " " + str
+	}
+	if j.alias != "" {
+		str += " " + quoteIdentifier(j.alias)
+	}
+	if j.on != nil {
+		str += " ON " + j.on.String()
+	}
+	return str
+}
+
+// ambiguousColumn marks a field map entry for a plain column name that more
+// than one joined sheet has.
+const ambiguousColumn = -1
+
+// table is a sheet read for a query, with the name its columns can be
+// qualified with.
+type table struct {
+	name    string
+	headers []string
+	rows    [][]interface{}
+}
+
+// Join adds an INNER JOIN with sheet, matching rows where leftColumn equals
+// rightColumn. Unqualified right-hand columns refer to sheet; columns of
+// either sheet may be qualified as "Sheet.Column" anywhere in the query.
+//
+//	client.From("Orders").Join("Users", "UserID", "ID")
+func (q *Query) Join(sheet, leftColumn, rightColumn string) *Query {
+	return q.addJoin("INNER", sheet, leftColumn, rightColumn)
+}
+
+// LeftJoin is Join, except that rows without a match in sheet are kept with
+// sheet's columns set to NULL.
+func (q *Query) LeftJoin(sheet, leftColumn, rightColumn string) *Query {
+	return q.addJoin("LEFT", sheet, leftColumn, rightColumn)
+}
+
+func (q *Query) addJoin(kind, sheet, leftColumn, rightColumn string) *Query {
+	right := &columnRef{name: rightColumn}
+	if !strings.HasPrefix(rightColumn, sheet+".") {
+		right.table = sheet
+	}
+
+	q.joins = append(q.joins, joinClause{
+		kind:  kind,
+		sheet: sheet,
+		on:    &comparison{op: "=", left: &columnRef{name: leftColumn}, right: right},
+	})
+	return q
+}
+
+// tableRanges returns the ranges of the sheet the query is on and of every
+// joined sheet, leaving out common table expressions.
+func (q *Query) tableRanges() []string {
+	var ranges []string
+	for i, c := range q.tableCTEs() {
+		if c != nil {
+			continue
+		}
+		sheet := q.sheetName
+		if i > 0 {
+			sheet = q.joins[i-1].sheet
+		}
+		ranges = append(ranges, fmt.Sprintf("%s!A:Z", sheet))
+	}
+	return ranges
+}
+
+// tableCTEs returns the common table expression read for the query's table
+// and for each joined table, or nil for those that are sheets.
+func (q *Query) tableCTEs() []*cte {
+	ctes := []*cte{q.cte}
+	for _, j := range q.joins {
+		ctes = append(ctes, j.cte)
+	}
+	return ctes
+}
+
+// readTables reads the sheet the query is on and every joined sheet. Sheets
+// not read before in exec are fetched together in a single request.
+func (q *Query) readTables(exec *execution) ([]*table, error) {
+	tables := []*table{{name: q.sheetName}}
+	if q.alias != "" {
+		tables[0].name = q.alias
+	}
+
+	for _, j := range q.joins {
+		t := &table{name: j.sheet}
+		if j.alias != "" {
+			t.name = j.alias
+		}
+		tables = append(tables, t)
+	}
+
+	seen := make(map[string]bool)
+	for _, t := range tables {
+		if seen[t.name] {
+			return nil, fmt.Errorf("table name %q specified more than once", t.name)
+		}
+		seen[t.name] = true
+	}
+
+	if _, err := exec.read(q.sheetRanges()); err != nil {
+		return nil, err
+	}
+	sheets, err := exec.read(q.tableRanges())
+	if err != nil {
+		return nil, err
+	}
+
+	for i, c := range q.tableCTEs() {
+		var values [][]interface{}
+		if c != nil {
+			if values, err = exec.cteValues(c); err != nil {
+				return nil, err
+			}
+		} else if len(sheets) > 0 {
+			values, sheets = sheets[0], sheets[1:]
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		t := tables[i]
+		t.headers = make([]string, len(values[0]))
+		for j, header := range values[0] {
+			t.headers[j] = fmt.Sprintf("%v", header)
+		}
+		t.rows = values[1:]
+
+		if i == 0 && q.pivot != nil {
+			if err := q.pivotTable(exec, t); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return tables, nil
+}
+
+// joinHeaders returns the column names of the joined tables and a field map
+// from both plain and qualified names to positions in a joined row. A plain
+// name found in more than one table maps to -1 and is reported as ambiguous;
+// in the returned names such columns are qualified.
+func joinHeaders(tables []*table) ([]string, map[string]int) {
+	fieldMap := make(map[string]int)
+	owner := make(map[string]string)
+
+	offset := 0
+	for _, t := range tables {
+		for i, header := range t.headers {
+			if prev, exists := owner[header]; exists && prev != t.name {
+				fieldMap[header] = ambiguousColumn
+			} else {
+				fieldMap[header] = offset + i
+				owner[header] = t.name
+			}
+			fieldMap[t.name+"."+header] = offset + i
+		}
+		offset += len(t.headers)
+	}
+
+	var headers []string
+	for _, t := range tables {
+		for _, header := range t.headers {
+			if fieldMap[header] == ambiguousColumn {
+				header = t.name + "." + header
+			}
+			headers = append(headers, header)
+		}
+	}
+
+	return headers, fieldMap
+}
+
+// joinRows joins the tables in order, one hash join per JOIN clause, and
+// returns the joined rows.
+func (q *Query) joinRows(tables []*table, base *evalEnv) ([][]interface{}, error) {
+	fieldMap := base.fieldMap
+
+	rows := make([][]interface{}, len(tables[0].rows))
+	for i, row := range tables[0].rows {
+		rows[i] = padRow(row, len(tables[0].headers))
+	}
+	width := len(tables[0].headers)
+
+	for i, j := range q.joins {
+		right := tables[i+1]
+		if err := checkColumns(j.on, fieldMap, "ON"); err != nil {
+			return nil, err
+		}
+
+		leftKeys, rightKeys, residual := splitJoinCondition(j.on, fieldMap, width, width+len(right.headers))
+
+		// Build a hash table over the right-hand rows, placed where they
+		// will be in the joined row so that their keys can be evaluated.
+		buckets := make(map[string][][]interface{})
+		for _, row := range right.rows {
+			joined := append(make([]interface{}, width), padRow(row, len(right.headers))...)
+			key, ok, err := q.joinKey(rightKeys, base.with(joined))
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				buckets[key] = append(buckets[key], joined[width:])
+			}
+		}
+
+		var result [][]interface{}
+		for _, left := range rows {
+			key, ok, err := q.joinKey(leftKeys, base.with(left))
+			if err != nil {
+				return nil, err
+			}
+
+			matched := false
+			if ok {
+				for _, candidate := range buckets[key] {
+					joined := append(append(make([]interface{}, 0, width+len(candidate)), left...), candidate...)
+					if residual != nil {
+						keep, err := evalCondition(residual, base.with(joined))
+						if err != nil {
+							return nil, err
+						}
+						if keep != true {
+							continue
+						}
+					}
+					result = append(result, joined)
+					matched = true
+				}
+			}
+
+			if !matched && j.kind == "LEFT" {
+				result = append(result, append(append([]interface{}{}, left...), make([]interface{}, len(right.headers))...))
+			}
+		}
+
+		rows = result
+		width += len(right.headers)
+	}
+
+	return rows, nil
+}
+
+// joinKey evaluates the equi-join keys of row. ok is false when a key is
+// NULL, which never equals anything.
+func (q *Query) joinKey(keys []expr, env *evalEnv) (string, bool, error) {
+	parts := make([]string, len(keys))
+	for i, e := range keys {
+		value, err := e.eval(env)
+		if err != nil {
+			return "", false, err
+		}
+		if value == nil {
+			return "", false, nil
+		}
+		parts[i] = formatValue(value)
+	}
+	return strings.Join(parts, "\x1f"), true, nil
+}
+
+// splitJoinCondition picks out the equalities in an ON condition that compare
+// a column of the left-hand rows with one of the joined table, whose columns
+// lie in [start, end). These become hash keys; the rest of the condition is
+// returned as residual and checked for each matching pair. Without any
+// equalities every pair of rows is a candidate.
+func splitJoinCondition(on expr, fieldMap map[string]int, start, end int) (leftKeys, rightKeys []expr, residual expr) {
+	side := func(e expr) int {
+		ref, ok := e.(*columnRef)
+		if !ok {
+			return 0
+		}
+		switch colIndex := fieldMap[ref.key()]; {
+		case colIndex >= start && colIndex < end:
+			return 1
+		case colIndex >= 0 && colIndex < start:
+			return -1
+		}
+		return 0
+	}
+
+	for _, cond := range conjuncts(on) {
+		if c, ok := cond.(*comparison); ok && (c.op == "=" || c.op == "==") {
+			switch {
+			case side(c.left) < 0 && side(c.right) > 0:
+				leftKeys, rightKeys = append(leftKeys, c.left), append(rightKeys, c.right)
+				continue
+			case side(c.left) > 0 && side(c.right) < 0:
+				leftKeys, rightKeys = append(leftKeys, c.right), append(rightKeys, c.left)
+				continue
+			}
+		}
+		residual = and(residual, cond)
+	}
+
+	return leftKeys, rightKeys, residual
+}
+
+// conjuncts flattens a chain of ANDs into its operands.
+func conjuncts(e expr) []expr {
+	if l, ok := e.(*logicalExpr); ok && l.op == "AND" {
+		return append(conjuncts(l.left), conjuncts(l.right)...)
+	}
+	if e == nil {
+		return nil
+	}
+	return []expr{e}
+}
+
+// padRow extends row with NULLs to width cells.
+func padRow(row []interface{}, width int) []interface{} {
+	padded := make([]interface{}, width)
+	copy(padded, row)
+	return padded
+}
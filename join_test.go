@@ -0,0 +1,196 @@
+package sheetsql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// countingBackend records how many read and append requests a query makes.
+type countingBackend struct {
+	*MemoryBackend
+	reads   int
+	appends int
+}
+
+func (b *countingBackend) ReadRange(spreadsheetID, readRange string) ([][]interface{}, error) {
+	b.reads++
+	return b.MemoryBackend.ReadRange(spreadsheetID, readRange)
+}
+
+func (b *countingBackend) ReadRanges(spreadsheetID string, readRanges []string) ([][][]interface{}, error) {
+	b.reads++
+	return b.MemoryBackend.ReadRanges(spreadsheetID, readRanges)
+}
+
+func (b *countingBackend) AppendRows(spreadsheetID, writeRange string, rows [][]interface{}) error {
+	b.appends++
+	return b.MemoryBackend.AppendRows(spreadsheetID, writeRange, rows)
+}
+
+func newJoinTestBackend() *countingBackend {
+	backend := &countingBackend{MemoryBackend: NewMemoryBackend()}
+	backend.SetSheet("Users", [][]interface{}{
+		{"ID", "Name", "TeamID"},
+		{"1", "Ann", "10"},
+		{"2", "Ben", "20"},
+		{"3", "Cat"},
+	})
+	backend.SetSheet("Orders", [][]interface{}{
+		{"ID", "UserID", "Total"},
+		{"100", "1", "5"},
+		{"101", "2", "7"},
+		{"102", "1", "9"},
+		{"103", "4", "1"},
+	})
+	backend.SetSheet("Teams", [][]interface{}{
+		{"TeamID", "Team"},
+		{"10", "red"},
+		{"20", "blue"},
+	})
+	return backend
+}
+
+func TestQuery_Join(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		columns  []string
+		expected [][]interface{}
+		wantErr  string
+	}{
+		{
+			name:     "inner join",
+			sql:      "SELECT Orders.ID, Name, Total FROM Orders JOIN Users ON Orders.UserID = Users.ID",
+			columns:  []string{"ID", "Name", "Total"},
+			expected: [][]interface{}{{"100", "Ann", "5"}, {"101", "Ben", "7"}, {"102", "Ann", "9"}},
+		},
+		{
+			name:     "left join keeps unmatched rows",
+			sql:      "SELECT o.ID, u.Name FROM Orders o LEFT JOIN Users AS u ON u.ID = o.UserID WHERE o.Total < 8",
+			columns:  []string{"ID", "Name"},
+			expected: [][]interface{}{{"100", "Ann"}, {"101", "Ben"}, {"103", nil}},
+		},
+		{
+			name:     "three tables",
+			sql:      "SELECT Name, Team FROM Users INNER JOIN Teams ON Users.TeamID = Teams.TeamID JOIN Orders ON Orders.UserID = Users.ID ORDER BY Orders.ID DESC",
+			columns:  []string{"Name", "Team"},
+			expected: [][]interface{}{{"Ann", "red"}, {"Ben", "blue"}, {"Ann", "red"}},
+		},
+		{
+			name:     "residual condition",
+			sql:      "SELECT Orders.ID FROM Users JOIN Orders ON Orders.UserID = Users.ID AND Total > 6",
+			columns:  []string{"ID"},
+			expected: [][]interface{}{{"102"}, {"101"}},
+		},
+		{
+			name:     "cross join",
+			sql:      "SELECT Name, Team FROM Users CROSS JOIN Teams WHERE Users.ID = '1'",
+			columns:  []string{"Name", "Team"},
+			expected: [][]interface{}{{"Ann", "red"}, {"Ann", "blue"}},
+		},
+		{
+			name:     "star qualifies ambiguous columns",
+			sql:      "SELECT * FROM Users, Teams WHERE Name = 'Cat' AND Team = 'red'",
+			columns:  []string{"ID", "Name", "Users.TeamID", "Teams.TeamID", "Team"},
+			expected: [][]interface{}{{"3", "Cat", nil, "10", "red"}},
+		},
+		{
+			name:     "aggregate over join",
+			sql:      "SELECT Name, SUM(Total) AS Spent FROM Users JOIN Orders ON Orders.UserID = Users.ID GROUP BY Name ORDER BY Spent DESC",
+			columns:  []string{"Name", "Spent"},
+			expected: [][]interface{}{{"Ann", int64(14)}, {"Ben", int64(7)}},
+		},
+		{
+			name:    "ambiguous column",
+			sql:     "SELECT ID FROM Orders JOIN Users ON UserID = Users.ID",
+			wantErr: `ambiguous column "ID" in select list`,
+		},
+		{
+			name:    "unknown column in ON",
+			sql:     "SELECT Name FROM Orders JOIN Users ON Orders.Customer = Users.ID",
+			wantErr: `unknown column "Orders.Customer" in ON`,
+		},
+		{
+			name:    "duplicate table",
+			sql:     "SELECT * FROM Users JOIN Users ON Users.ID = Users.ID",
+			wantErr: `table name "Users" specified more than once`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newJoinTestBackend()
+			query, err := NewSQLParser(NewClientWithBackend("test-id", backend)).parseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("parseSQL() error = %v", err)
+			}
+
+			columns, rows, err := query.rows()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("rows() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rows() error = %v", err)
+			}
+			if !reflect.DeepEqual(columns, tt.columns) {
+				t.Errorf("columns = %v, expected %v", columns, tt.columns)
+			}
+			if !reflect.DeepEqual(rows, tt.expected) {
+				t.Errorf("rows = %v, expected %v", rows, tt.expected)
+			}
+			if backend.reads != 1 {
+				t.Errorf("query made %d read requests, expected 1", backend.reads)
+			}
+		})
+	}
+}
+
+func TestQuery_Join_Fluent(t *testing.T) {
+	client := NewClientWithBackend("test-id", newJoinTestBackend())
+
+	type OrderRow struct {
+		ID    int    `sheet:"Orders.ID"`
+		Name  string `sheet:"Name"`
+		Total int    `sheet:"Total"`
+	}
+
+	var orders []OrderRow
+	err := client.From("Orders").
+		Join("Users", "UserID", "ID").
+		Where("Name", "=", "Ann").
+		Get(&orders)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	expected := []OrderRow{{ID: 100, Name: "Ann", Total: 5}, {ID: 102, Name: "Ann", Total: 9}}
+	if !reflect.DeepEqual(orders, expected) {
+		t.Errorf("Get() = %+v, expected %+v", orders, expected)
+	}
+
+	sqlQuery, err := NewSQLParser(client).parseSQL("SELECT * FROM Orders JOIN Users ON UserID = Users.ID")
+	if err != nil {
+		t.Fatalf("parseSQL() error = %v", err)
+	}
+	fluent := client.From("Orders").Join("Users", "UserID", "ID")
+	if !reflect.DeepEqual(fluent.joins, sqlQuery.joins) {
+		t.Errorf("Join() = %+v, expected the same plan as SQL %+v", fluent.joins, sqlQuery.joins)
+	}
+
+	_, rows, err := client.From("Orders").LeftJoin("Users", "Orders.UserID", "Users.ID").Select("Name").rows()
+	if err != nil {
+		t.Fatalf("rows() error = %v", err)
+	}
+	if !reflect.DeepEqual(rows, [][]interface{}{{"Ann"}, {"Ben"}, {"Ann"}, {nil}}) {
+		t.Errorf("LeftJoin() rows = %v", rows)
+	}
+
+	err = client.From("Orders").Join("Users", "UserID", "ID").Where("Total", "=", 5).Delete()
+	if err == nil || !strings.Contains(err.Error(), "joins") {
+		t.Errorf("Delete() error = %v, expected joins to be rejected", err)
+	}
+}
@@ -0,0 +1,274 @@
+package sheetsql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SyntaxError reports a problem in SQL text and where it was found. Line and
+// Column are 1-based and count characters, not bytes.
+type SyntaxError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOperator
+	tokParam
+)
+
+type position struct {
+	line   int
+	column int
+}
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  position
+
+	// quoted is set for identifiers written as `name` or [name]; they are
+	// never treated as keywords.
+	quoted bool
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of input"
+	case tokString:
+		return fmt.Sprintf("string '%s'", t.text)
+	case tokIdent:
+		if t.quoted {
+			return fmt.Sprintf("identifier `%s`", t.text)
+		}
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// operators lists the operator and punctuation tokens, longest first so that
+// "<=" is preferred over "<".
+var operators = []string{
+	"<=", ">=", "<>", "!=", "!~", "==", "||",
+	"=", "<", ">", "~", "+", "-", "*", "/", "%", "(", ")", ",", ".", ";",
+}
+
+type lexer struct {
+	input string
+	off   int
+	pos   position
+}
+
+// tokenize splits SQL text into tokens, ending with a tokEOF token.
+func tokenize(input string) ([]token, error) {
+	l := &lexer{input: input, pos: position{line: 1, column: 1}}
+
+	var tokens []token
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+		if tok.kind == tokEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (l *lexer) errorf(pos position, format string, args ...interface{}) error {
+	return &SyntaxError{Line: pos.line, Column: pos.column, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (l *lexer) peek() rune {
+	if l.off >= len(l.input) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(l.input[l.off:])
+	return r
+}
+
+func (l *lexer) advance() rune {
+	r, size := utf8.DecodeRuneInString(l.input[l.off:])
+	l.off += size
+	if r == '\n' {
+		l.pos.line++
+		l.pos.column = 1
+	} else {
+		l.pos.column++
+	}
+	return r
+}
+
+func (l *lexer) skipSpaceAndComments() error {
+	for l.off < len(l.input) {
+		switch {
+		case unicode.IsSpace(l.peek()):
+			l.advance()
+		case strings.HasPrefix(l.input[l.off:], "--"):
+			for l.off < len(l.input) && l.peek() != '\n' {
+				l.advance()
+			}
+		case strings.HasPrefix(l.input[l.off:], "/*"):
+			start := l.pos
+			l.advance()
+			l.advance()
+			for !strings.HasPrefix(l.input[l.off:], "*/") {
+				if l.off >= len(l.input) {
+					return l.errorf(start, "unterminated comment")
+				}
+				l.advance()
+			}
+			l.advance()
+			l.advance()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (l *lexer) next() (token, error) {
+	if err := l.skipSpaceAndComments(); err != nil {
+		return token{}, err
+	}
+
+	start := l.pos
+	if l.off >= len(l.input) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	r := l.peek()
+	switch {
+	case r == '_' || unicode.IsLetter(r):
+		begin := l.off
+		for l.off < len(l.input) && (l.peek() == '_' || unicode.IsLetter(l.peek()) || unicode.IsDigit(l.peek())) {
+			l.advance()
+		}
+		return token{kind: tokIdent, text: l.input[begin:l.off], pos: start}, nil
+	case r >= '0' && r <= '9' || r == '.' && l.off+1 < len(l.input) && isDigit(l.input[l.off+1]):
+		return l.number(start)
+	case r == '\'' || r == '"':
+		text, err := l.quoted(r, r, "string")
+		if err != nil {
+			return token{}, err
+		}
+		return token{kind: tokString, text: text, pos: start}, nil
+	case r == '`':
+		text, err := l.quoted('`', '`', "identifier")
+		if err != nil {
+			return token{}, err
+		}
+		return token{kind: tokIdent, text: text, pos: start, quoted: true}, nil
+	case r == '[':
+		text, err := l.quoted('[', ']', "identifier")
+		if err != nil {
+			return token{}, err
+		}
+		return token{kind: tokIdent, text: text, pos: start, quoted: true}, nil
+	case r == '?' || r == '$' || r == ':':
+		return l.param(start)
+	}
+
+	for _, op := range operators {
+		if strings.HasPrefix(l.input[l.off:], op) {
+			for range op {
+				l.advance()
+			}
+			return token{kind: tokOperator, text: op, pos: start}, nil
+		}
+	}
+
+	return token{}, l.errorf(start, "unexpected character %q", r)
+}
+
+func (l *lexer) number(start position) (token, error) {
+	begin := l.off
+	for l.off < len(l.input) && isDigit(l.input[l.off]) {
+		l.advance()
+	}
+	if l.off < len(l.input) && l.input[l.off] == '.' {
+		l.advance()
+		for l.off < len(l.input) && isDigit(l.input[l.off]) {
+			l.advance()
+		}
+	}
+	if l.off < len(l.input) && (l.input[l.off] == 'e' || l.input[l.off] == 'E') {
+		l.advance()
+		if l.off < len(l.input) && (l.input[l.off] == '+' || l.input[l.off] == '-') {
+			l.advance()
+		}
+		if l.off >= len(l.input) || !isDigit(l.input[l.off]) {
+			return token{}, l.errorf(l.pos, "malformed number %q", l.input[begin:l.off])
+		}
+		for l.off < len(l.input) && isDigit(l.input[l.off]) {
+			l.advance()
+		}
+	}
+	if r := l.peek(); r == '_' || unicode.IsLetter(r) {
+		return token{}, l.errorf(start, "malformed number %q", l.input[begin:l.off+utf8.RuneLen(r)])
+	}
+	return token{kind: tokNumber, text: l.input[begin:l.off], pos: start}, nil
+}
+
+// param reads a placeholder: ?, $ followed by a number, or : followed by a
+// name.
+func (l *lexer) param(start position) (token, error) {
+	begin := l.off
+	r := l.advance()
+	switch r {
+	case '$':
+		for l.off < len(l.input) && isDigit(l.input[l.off]) {
+			l.advance()
+		}
+	case ':':
+		for l.off < len(l.input) && (l.peek() == '_' || unicode.IsLetter(l.peek()) || l.off > begin+1 && unicode.IsDigit(l.peek())) {
+			l.advance()
+		}
+	}
+	if r != '?' && l.off == begin+1 {
+		return token{}, l.errorf(start, "unexpected character %q", r)
+	}
+	return token{kind: tokParam, text: l.input[begin:l.off], pos: start}, nil
+}
+
+// quoted reads text between open and close, where a doubled close character
+// stands for itself, as in SQL string literals.
+func (l *lexer) quoted(open, close rune, what string) (string, error) {
+	start := l.pos
+	l.advance()
+
+	var sb strings.Builder
+	for {
+		if l.off >= len(l.input) {
+			return "", l.errorf(start, "unterminated %s", what)
+		}
+		r := l.advance()
+		if r == close {
+			if l.peek() == close && open == close {
+				l.advance()
+				sb.WriteRune(close)
+				continue
+			}
+			return sb.String(), nil
+		}
+		sb.WriteRune(r)
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
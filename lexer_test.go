@@ -0,0 +1,132 @@
+package sheetsql
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []token
+	}{
+		{
+			name:  "keywords and operators",
+			input: "SELECT * FROM Users WHERE Age >= 18",
+			expected: []token{
+				{kind: tokIdent, text: "SELECT"},
+				{kind: tokOperator, text: "*"},
+				{kind: tokIdent, text: "FROM"},
+				{kind: tokIdent, text: "Users"},
+				{kind: tokIdent, text: "WHERE"},
+				{kind: tokIdent, text: "Age"},
+				{kind: tokOperator, text: ">="},
+				{kind: tokNumber, text: "18"},
+				{kind: tokEOF},
+			},
+		},
+		{
+			name:  "quoted identifiers",
+			input: "`First Name` [Last Name]",
+			expected: []token{
+				{kind: tokIdent, text: "First Name", quoted: true},
+				{kind: tokIdent, text: "Last Name", quoted: true},
+				{kind: tokEOF},
+			},
+		},
+		{
+			name:  "strings with escapes",
+			input: `'it''s' "say ""hi"""`,
+			expected: []token{
+				{kind: tokString, text: "it's"},
+				{kind: tokString, text: `say "hi"`},
+				{kind: tokEOF},
+			},
+		},
+		{
+			name:  "numbers",
+			input: "42 3.14 .5 1e3",
+			expected: []token{
+				{kind: tokNumber, text: "42"},
+				{kind: tokNumber, text: "3.14"},
+				{kind: tokNumber, text: ".5"},
+				{kind: tokNumber, text: "1e3"},
+				{kind: tokEOF},
+			},
+		},
+		{
+			name:  "placeholders",
+			input: "? $12 :name :a1 $1+?",
+			expected: []token{
+				{kind: tokParam, text: "?"},
+				{kind: tokParam, text: "$12"},
+				{kind: tokParam, text: ":name"},
+				{kind: tokParam, text: ":a1"},
+				{kind: tokParam, text: "$1"},
+				{kind: tokOperator, text: "+"},
+				{kind: tokParam, text: "?"},
+				{kind: tokEOF},
+			},
+		},
+		{
+			name:  "comments",
+			input: "a -- trailing\n/* block */ b",
+			expected: []token{
+				{kind: tokIdent, text: "a"},
+				{kind: tokIdent, text: "b"},
+				{kind: tokEOF},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := tokenize(tt.input)
+			if err != nil {
+				t.Fatalf("tokenize() error = %v", err)
+			}
+
+			if len(tokens) != len(tt.expected) {
+				t.Fatalf("tokenize() returned %d tokens, expected %d: %v", len(tokens), len(tt.expected), tokens)
+			}
+
+			for i, expected := range tt.expected {
+				actual := tokens[i]
+				if actual.kind != expected.kind || actual.text != expected.text || actual.quoted != expected.quoted {
+					t.Errorf("token %d = %+v, expected %+v", i, actual, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenize_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		line   int
+		column int
+	}{
+		{"unterminated string", "SELECT 'abc", 1, 8},
+		{"unterminated identifier", "SELECT\n  [abc", 2, 3},
+		{"unexpected character", "SELECT # FROM t", 1, 8},
+		{"malformed number", "LIMIT 10x", 1, 7},
+		{"dollar without number", "WHERE a = $x", 1, 11},
+		{"colon without name", "WHERE a = : b", 1, 11},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tokenize(tt.input)
+
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("tokenize() error = %v, expected *SyntaxError", err)
+			}
+			if syntaxErr.Line != tt.line || syntaxErr.Column != tt.column {
+				t.Errorf("error at line %d, column %d, expected line %d, column %d", syntaxErr.Line, syntaxErr.Column, tt.line, tt.column)
+			}
+		})
+	}
+}
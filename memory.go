@@ -0,0 +1,376 @@
+package sheetsql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// MemoryBackend is a Backend that keeps a single spreadsheet in memory. It
+// mirrors the Sheets API closely enough for Query and SQLParser to run
+// against it unchanged: reads trim trailing empty cells and rows, appends go
+// after the last non-empty row and deleted rows shift the rows below them up.
+type MemoryBackend struct {
+	mu     sync.RWMutex
+	sheets []*memorySheet
+	nextID int64
+}
+
+type memorySheet struct {
+	id    int64
+	title string
+	rows  [][]interface{}
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{}
+}
+
+// SetSheet creates the named sheet, or replaces its contents if it exists.
+func (m *MemoryBackend) SetSheet(name string, rows [][]interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if sheet := m.sheet(name); sheet != nil {
+		sheet.rows = copyRows(rows)
+		return
+	}
+
+	m.sheets = append(m.sheets, &memorySheet{
+		id:    m.nextID,
+		title: name,
+		rows:  copyRows(rows),
+	})
+	m.nextID++
+}
+
+// Sheet returns a copy of the named sheet's rows, or nil if it does not exist.
+func (m *MemoryBackend) Sheet(name string) [][]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sheet := m.sheet(name)
+	if sheet == nil {
+		return nil
+	}
+	return copyRows(sheet.rows)
+}
+
+func (m *MemoryBackend) ReadRange(spreadsheetID, readRange string) ([][]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	r, err := parseA1Range(readRange)
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := m.sheet(r.sheet)
+	if sheet == nil {
+		return nil, fmt.Errorf("unable to parse range: %s", readRange)
+	}
+
+	var values [][]interface{}
+	for i, row := range sheet.rows {
+		rowNum := i + 1
+		if rowNum < r.startRow || (r.endRow > 0 && rowNum > r.endRow) {
+			continue
+		}
+		values = append(values, trimRow(clipRow(row, r.startCol, r.endCol)))
+	}
+
+	for len(values) > 0 && len(values[len(values)-1]) == 0 {
+		values = values[:len(values)-1]
+	}
+
+	return values, nil
+}
+
+func (m *MemoryBackend) ReadRanges(spreadsheetID string, readRanges []string) ([][][]interface{}, error) {
+	values := make([][][]interface{}, len(readRanges))
+	for i, readRange := range readRanges {
+		var err error
+		if values[i], err = m.ReadRange(spreadsheetID, readRange); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func (m *MemoryBackend) AppendRows(spreadsheetID, writeRange string, rows [][]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, err := parseA1Range(writeRange)
+	if err != nil {
+		return err
+	}
+
+	sheet := m.sheet(r.sheet)
+	if sheet == nil {
+		return fmt.Errorf("unable to parse range: %s", writeRange)
+	}
+
+	last := len(sheet.rows)
+	for last > 0 && len(trimRow(sheet.rows[last-1])) == 0 {
+		last--
+	}
+	sheet.rows = sheet.rows[:last]
+
+	for _, row := range rows {
+		newRow := make([]interface{}, r.startCol+len(row))
+		copy(newRow[r.startCol:], row)
+		sheet.rows = append(sheet.rows, newRow)
+	}
+
+	return nil
+}
+
+func (m *MemoryBackend) UpdateRange(spreadsheetID, updateRange string, rows [][]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, err := parseA1Range(updateRange)
+	if err != nil {
+		return err
+	}
+
+	sheet := m.sheet(r.sheet)
+	if sheet == nil {
+		return fmt.Errorf("unable to parse range: %s", updateRange)
+	}
+
+	for i, row := range rows {
+		rowIndex := r.startRow - 1 + i
+		for len(sheet.rows) <= rowIndex {
+			sheet.rows = append(sheet.rows, nil)
+		}
+
+		for j, value := range row {
+			// Like the Sheets API, null values leave the existing cell as is.
+			if value == nil {
+				continue
+			}
+
+			colIndex := r.startCol + j
+			if r.endCol >= 0 && colIndex > r.endCol {
+				return fmt.Errorf("row %d does not fit in range %s", rowIndex+1, updateRange)
+			}
+			for len(sheet.rows[rowIndex]) <= colIndex {
+				sheet.rows[rowIndex] = append(sheet.rows[rowIndex], nil)
+			}
+			sheet.rows[rowIndex][colIndex] = value
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryBackend) BatchUpdate(spreadsheetID string, req *sheets.BatchUpdateSpreadsheetRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, request := range req.Requests {
+		if request.DeleteDimension == nil {
+			return fmt.Errorf("unsupported batch update request")
+		}
+
+		dimRange := request.DeleteDimension.Range
+		if dimRange == nil {
+			return fmt.Errorf("delete dimension request has no range")
+		}
+
+		sheet := m.sheetByID(dimRange.SheetId)
+		if sheet == nil {
+			return fmt.Errorf("no sheet with id %d", dimRange.SheetId)
+		}
+
+		start, end := int(dimRange.StartIndex), int(dimRange.EndIndex)
+		if start < 0 || end < start {
+			return fmt.Errorf("invalid dimension range %d:%d", start, end)
+		}
+
+		switch dimRange.Dimension {
+		case "ROWS":
+			if start >= len(sheet.rows) {
+				continue
+			}
+			if end > len(sheet.rows) {
+				end = len(sheet.rows)
+			}
+			sheet.rows = append(sheet.rows[:start], sheet.rows[end:]...)
+		case "COLUMNS":
+			for i, row := range sheet.rows {
+				if start >= len(row) {
+					continue
+				}
+				rowEnd := end
+				if rowEnd > len(row) {
+					rowEnd = len(row)
+				}
+				sheet.rows[i] = append(row[:start], row[rowEnd:]...)
+			}
+		default:
+			return fmt.Errorf("unsupported dimension: %s", dimRange.Dimension)
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryBackend) SheetProperties(spreadsheetID string) ([]*sheets.SheetProperties, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	props := make([]*sheets.SheetProperties, len(m.sheets))
+	for i, sheet := range m.sheets {
+		props[i] = &sheets.SheetProperties{
+			SheetId: sheet.id,
+			Title:   sheet.title,
+			Index:   int64(i),
+		}
+	}
+	return props, nil
+}
+
+func (m *MemoryBackend) sheet(name string) *memorySheet {
+	for _, sheet := range m.sheets {
+		if sheet.title == name {
+			return sheet
+		}
+	}
+	return nil
+}
+
+func (m *MemoryBackend) sheetByID(id int64) *memorySheet {
+	for _, sheet := range m.sheets {
+		if sheet.id == id {
+			return sheet
+		}
+	}
+	return nil
+}
+
+func copyRows(rows [][]interface{}) [][]interface{} {
+	if rows == nil {
+		return nil
+	}
+
+	copied := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		copied[i] = append([]interface{}(nil), row...)
+	}
+	return copied
+}
+
+func clipRow(row []interface{}, startCol, endCol int) []interface{} {
+	if startCol >= len(row) {
+		return nil
+	}
+	if endCol >= 0 && endCol+1 < len(row) {
+		row = row[:endCol+1]
+	}
+	return append([]interface{}(nil), row[startCol:]...)
+}
+
+func trimRow(row []interface{}) []interface{} {
+	end := len(row)
+	for end > 0 && (row[end-1] == nil || row[end-1] == "") {
+		end--
+	}
+
+	trimmed := make([]interface{}, end)
+	for i, value := range row[:end] {
+		if value == nil {
+			value = ""
+		}
+		trimmed[i] = value
+	}
+	return trimmed
+}
+
+// a1Range is a parsed A1 range. Rows are 1-based with endRow 0 meaning
+// unbounded; columns are 0-based with endCol -1 meaning unbounded.
+type a1Range struct {
+	sheet    string
+	startRow int
+	endRow   int
+	startCol int
+	endCol   int
+}
+
+func parseA1Range(s string) (a1Range, error) {
+	r := a1Range{startRow: 1, endCol: -1}
+
+	name, cells := s, ""
+	if i := strings.LastIndex(s, "!"); i >= 0 {
+		name, cells = s[:i], s[i+1:]
+	}
+	if len(name) >= 2 && name[0] == '\'' && name[len(name)-1] == '\'' {
+		name = strings.ReplaceAll(name[1:len(name)-1], "''", "'")
+	}
+	if name == "" {
+		return r, fmt.Errorf("unable to parse range: %s", s)
+	}
+	r.sheet = name
+
+	if cells == "" {
+		return r, nil
+	}
+
+	start, end := cells, cells
+	if i := strings.Index(cells, ":"); i >= 0 {
+		start, end = cells[:i], cells[i+1:]
+	}
+
+	startCol, startRow, err := parseA1Cell(start)
+	if err != nil {
+		return r, fmt.Errorf("unable to parse range: %s", s)
+	}
+	endCol, endRow, err := parseA1Cell(end)
+	if err != nil {
+		return r, fmt.Errorf("unable to parse range: %s", s)
+	}
+
+	if startCol >= 0 {
+		r.startCol = startCol
+	}
+	r.endCol = endCol
+	if startRow > 0 {
+		r.startRow = startRow
+	}
+	r.endRow = endRow
+
+	return r, nil
+}
+
+// parseA1Cell splits a cell reference such as "B12", "B" or "12" into a
+// 0-based column (-1 if absent) and a 1-based row (0 if absent).
+func parseA1Cell(s string) (col, row int, err error) {
+	i := 0
+	for i < len(s) && isLetter(s[i]) {
+		col = col*26 + int(s[i]|0x20-'a') + 1
+		i++
+	}
+	col--
+
+	if i < len(s) {
+		row, err = strconv.Atoi(s[i:])
+		if err != nil || row < 1 {
+			return 0, 0, fmt.Errorf("invalid cell reference: %s", s)
+		}
+	}
+
+	if col < 0 && row == 0 {
+		return 0, 0, fmt.Errorf("invalid cell reference: %s", s)
+	}
+
+	return col, row, nil
+}
+
+func isLetter(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
@@ -0,0 +1,165 @@
+package sheetsql
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+type memoryUser struct {
+	ID    int    `sheet:"ID"`
+	Name  string `sheet:"Name"`
+	Email string `sheet:"Email"`
+	Age   int    `sheet:"Age"`
+	City  string `sheet:"City"`
+}
+
+func TestMemoryBackend_Query(t *testing.T) {
+	client := SetupTestData().Client("test-id")
+
+	var users []memoryUser
+	err := client.From("Users").
+		Where("City", "=", "New York").
+		Get(&users)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if len(users) != 2 || users[0].Name != "John Doe" || users[1].Name != "Alice Brown" {
+		t.Errorf("Get() = %+v, expected John Doe and Alice Brown", users)
+	}
+
+	parser := NewSQLParser(client)
+	users = nil
+	if err := parser.Query("SELECT * FROM Users WHERE Age > 26 LIMIT 2", &users); err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	if len(users) != 2 || users[0].ID != 1 || users[1].ID != 3 {
+		t.Errorf("Query() = %+v, expected users 1 and 3", users)
+	}
+}
+
+func TestMemoryBackend_InsertUpdateDelete(t *testing.T) {
+	mock := SetupTestData()
+	client := mock.Client("test-id")
+
+	err := client.From("Users").Insert(memoryUser{ID: 6, Name: "Dana White", Email: "dana@example.com", Age: 41, City: "Denver"})
+	if err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	err = client.From("Users").
+		Where("Name", "=", "Jane Smith").
+		Update(memoryUser{ID: 2, Name: "Jane Smith", Email: "jane@example.com", Age: 26, City: "Seattle"})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	err = client.From("Users").
+		Where("City", "=", "New York").
+		Delete()
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	var users []memoryUser
+	if err := client.From("Users").Get(&users); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	var names []string
+	for _, user := range users {
+		names = append(names, user.Name)
+	}
+
+	expected := []string{"Jane Smith", "Bob Johnson", "Charlie Wilson", "Dana White"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("rows after writes = %v, expected %v", names, expected)
+	}
+
+	if users[0].City != "Seattle" || users[0].Age != 26 {
+		t.Errorf("updated row = %+v", users[0])
+	}
+
+	if rows := mock.GetSheetData("Users"); len(rows) != 5 {
+		t.Errorf("expected 5 rows including header after delete, got %d", len(rows))
+	}
+}
+
+func TestMemoryBackend_ReadRange(t *testing.T) {
+	backend := NewMemoryBackend()
+	backend.SetSheet("Data", [][]interface{}{
+		{"A", "B", "C"},
+		{1, "", nil},
+		{2, 3},
+		{},
+	})
+
+	tests := []struct {
+		name      string
+		readRange string
+		expected  [][]interface{}
+	}{
+		{"whole sheet", "Data", [][]interface{}{{"A", "B", "C"}, {1}, {2, 3}}},
+		{"column range", "Data!A:Z", [][]interface{}{{"A", "B", "C"}, {1}, {2, 3}}},
+		{"header row", "Data!1:1", [][]interface{}{{"A", "B", "C"}}},
+		{"single row", "Data!A3:Z3", [][]interface{}{{2, 3}}},
+		{"column clip", "Data!B:B", [][]interface{}{{"B"}, {}, {3}}},
+		{"quoted sheet name", "'Data'!A1:A1", [][]interface{}{{"A"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := backend.ReadRange("id", tt.readRange)
+			if err != nil {
+				t.Fatalf("ReadRange() error = %v", err)
+			}
+			if !reflect.DeepEqual(values, tt.expected) {
+				t.Errorf("ReadRange(%q) = %v, expected %v", tt.readRange, values, tt.expected)
+			}
+		})
+	}
+
+	if _, err := backend.ReadRange("id", "Missing!A:Z"); err == nil {
+		t.Error("ReadRange() on a missing sheet expected error")
+	}
+}
+
+func TestMemoryBackend_SheetIDs(t *testing.T) {
+	backend := NewMemoryBackend()
+	backend.SetSheet("First", [][]interface{}{{"A"}, {1}, {2}, {3}})
+	backend.SetSheet("Second", [][]interface{}{{"B"}, {4}})
+
+	props, err := backend.SheetProperties("id")
+	if err != nil {
+		t.Fatalf("SheetProperties() error = %v", err)
+	}
+	if len(props) != 2 || props[0].SheetId == props[1].SheetId {
+		t.Fatalf("SheetProperties() = %+v, expected two sheets with distinct ids", props)
+	}
+
+	err = backend.BatchUpdate("id", &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{{
+			DeleteDimension: &sheets.DeleteDimensionRequest{
+				Range: &sheets.DimensionRange{
+					SheetId:    props[0].SheetId,
+					Dimension:  "ROWS",
+					StartIndex: 1,
+					EndIndex:   3,
+				},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("BatchUpdate() error = %v", err)
+	}
+
+	if rows := backend.Sheet("First"); !reflect.DeepEqual(rows, [][]interface{}{{"A"}, {3}}) {
+		t.Errorf("First after delete = %v", rows)
+	}
+	if rows := backend.Sheet("Second"); len(rows) != 2 {
+		t.Errorf("Second should be untouched, got %v", rows)
+	}
+}
@@ -0,0 +1,174 @@
+package sheetsql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// paramExpr is a placeholder whose value is bound when the statement runs:
+// ? and $n take the value at position index, :name the one called name.
+type paramExpr struct {
+	text  string
+	index int
+	name  string
+}
+
+func (e *paramExpr) eval(env *evalEnv) (interface{}, error) {
+	if env.exec == nil || env.exec.bindings == nil {
+		return nil, fmt.Errorf("no value bound to %s", e.text)
+	}
+	if e.name != "" {
+		return env.exec.bindings.named[e.name], nil
+	}
+	return env.exec.bindings.positional[e.index-1], nil
+}
+
+func (e *paramExpr) String() string {
+	return e.text
+}
+
+// placeholders describes the placeholders of a statement: how many
+// positional values it takes, or the names it uses.
+type placeholders struct {
+	count int
+	names []string
+}
+
+func (p placeholders) statementParams() placeholders { return p }
+
+// bindings holds the values of a statement's placeholders for one run.
+type bindings struct {
+	positional []interface{}
+	named      map[string]interface{}
+}
+
+// bind matches args to the placeholders. Positional placeholders take one
+// argument each. Named ones take sql.Named arguments, or a single map or
+// struct whose keys, sheet tags or field names are the names.
+func (p placeholders) bind(args []interface{}) (*bindings, error) {
+	if len(p.names) == 0 {
+		if len(args) != p.count {
+			return nil, fmt.Errorf("expected %d arguments, got %d", p.count, len(args))
+		}
+		b := &bindings{positional: make([]interface{}, len(args))}
+		for i, arg := range args {
+			value, err := bindValue(arg)
+			if err != nil {
+				return nil, fmt.Errorf("argument %d: %w", i+1, err)
+			}
+			b.positional[i] = value
+		}
+		return b, nil
+	}
+
+	values, err := namedArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	b := &bindings{named: make(map[string]interface{}, len(p.names))}
+	for _, name := range p.names {
+		value, ok := values[name]
+		if !ok {
+			return nil, fmt.Errorf("no value for :%s", name)
+		}
+		if b.named[name], err = bindValue(value); err != nil {
+			return nil, fmt.Errorf(":%s: %w", name, err)
+		}
+	}
+	return b, nil
+}
+
+// namedArgs collects the values of named arguments by name.
+func namedArgs(args []interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for _, arg := range args {
+		if named, ok := arg.(sql.NamedArg); ok {
+			values[named.Name] = named.Value
+			continue
+		}
+		if len(args) != 1 {
+			return nil, fmt.Errorf("named placeholders take sql.Named arguments or a single map or struct, got %T", arg)
+		}
+
+		v := reflect.ValueOf(arg)
+		for v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		switch {
+		case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+			iter := v.MapRange()
+			for iter.Next() {
+				values[iter.Key().String()] = iter.Value().Interface()
+			}
+		case v.Kind() == reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				field := v.Type().Field(i)
+				if !field.IsExported() {
+					continue
+				}
+				name := field.Tag.Get("sheet")
+				if name == "" {
+					name = field.Name
+				}
+				values[name] = v.Field(i).Interface()
+			}
+		default:
+			return nil, fmt.Errorf("named placeholders take sql.Named arguments or a single map or struct, got %T", arg)
+		}
+	}
+	return values, nil
+}
+
+// bindValue returns the value a placeholder stands for. Values are used as
+// they are, so a time.Time compares as a date and a number as a number;
+// only driver.Valuer implementations are converted.
+func bindValue(arg interface{}) (interface{}, error) {
+	if valuer, ok := arg.(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	return arg, nil
+}
+
+// addParam records a placeholder met while parsing. A statement must use a
+// single style of placeholder.
+func (p *parser) addParam(tok token) (*paramExpr, error) {
+	style := tok.text[:1]
+	if p.paramStyle != "" && p.paramStyle != style {
+		return nil, p.errorf(tok, "cannot mix %s and %s placeholders", styleName(p.paramStyle), styleName(style))
+	}
+	p.paramStyle = style
+
+	param := &paramExpr{text: tok.text}
+	switch style {
+	case "?":
+		p.params.count++
+		param.index = p.params.count
+	case "$":
+		n, err := strconv.Atoi(tok.text[1:])
+		if err != nil || n < 1 {
+			return nil, p.errorf(tok, "invalid placeholder %s", tok.text)
+		}
+		param.index = n
+		p.params.count = max(p.params.count, n)
+	default:
+		param.name = tok.text[1:]
+		if !slices.Contains(p.params.names, param.name) {
+			p.params.names = append(p.params.names, param.name)
+		}
+	}
+	return param, nil
+}
+
+func styleName(style string) string {
+	switch style {
+	case "?":
+		return "?"
+	case "$":
+		return "$n"
+	}
+	return ":name"
+}
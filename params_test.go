@@ -0,0 +1,200 @@
+package sheetsql
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSQLParser_Query_Params(t *testing.T) {
+	parser := NewSQLParser(SetupTestData().Client("test-id"))
+
+	type filter struct {
+		City   string `sheet:"city"`
+		MinAge int
+	}
+
+	tests := []struct {
+		name     string
+		sql      string
+		args     []interface{}
+		expected []int
+		wantErr  string
+	}{
+		{
+			name:     "question marks",
+			sql:      "SELECT * FROM Users WHERE City = ? AND Age > ?",
+			args:     []interface{}{"New York", 25},
+			expected: []int{1, 4},
+		},
+		{
+			name:     "numbered",
+			sql:      "SELECT * FROM Users WHERE Age BETWEEN $1 AND $2 OR ID = $1",
+			args:     []interface{}{30, 35},
+			expected: []int{1, 3},
+		},
+		{
+			name:     "numbers compare as numbers",
+			sql:      "SELECT * FROM Users WHERE Age >= ?",
+			args:     []interface{}{100},
+			expected: nil,
+		},
+		{
+			name:     "quotes are not mangled",
+			sql:      "SELECT * FROM Users WHERE Name IN (?, ?)",
+			args:     []interface{}{"John Doe", `Bob's "Diner"`},
+			expected: []int{1},
+		},
+		{
+			name:     "no injection",
+			sql:      "SELECT * FROM Users WHERE Name = ?",
+			args:     []interface{}{"x' OR '1'='1"},
+			expected: nil,
+		},
+		{
+			name:     "patterns and expressions",
+			sql:      "SELECT * FROM Users WHERE Name LIKE ? AND Age * ? > 50",
+			args:     []interface{}{"J%", 2},
+			expected: []int{1},
+		},
+		{
+			name:     "nil is null",
+			sql:      "SELECT * FROM Users WHERE City = ? OR COALESCE(?, City) = 'Boston'",
+			args:     []interface{}{nil, nil},
+			expected: []int{5},
+		},
+		{
+			name:     "valuer",
+			sql:      "SELECT * FROM Users WHERE City = ? OR Age = ?",
+			args:     []interface{}{sql.NullString{}, sql.NullInt64{Int64: 22, Valid: true}},
+			expected: []int{5},
+		},
+		{
+			name:     "named with a map",
+			sql:      "SELECT * FROM Users WHERE City = :city OR City = :other OR Name = :city",
+			args:     []interface{}{map[string]string{"city": "Boston", "other": "Chicago"}},
+			expected: []int{3, 5},
+		},
+		{
+			name:     "named with a struct",
+			sql:      "SELECT * FROM Users WHERE City = :city AND Age > :MinAge",
+			args:     []interface{}{&filter{City: "New York", MinAge: 29}},
+			expected: []int{1},
+		},
+		{
+			name:     "sql.Named",
+			sql:      "SELECT * FROM Users WHERE Age > :age AND City <> :city",
+			args:     []interface{}{sql.Named("age", 25), sql.Named("city", "Chicago")},
+			expected: []int{1, 4},
+		},
+		{
+			name:     "subquery",
+			sql:      "SELECT * FROM Users WHERE Age = (SELECT MAX(Age) FROM Users WHERE City = ?)",
+			args:     []interface{}{"New York"},
+			expected: []int{1},
+		},
+		{
+			name:    "too few arguments",
+			sql:     "SELECT * FROM Users WHERE City = ? AND Age > ?",
+			args:    []interface{}{"Boston"},
+			wantErr: "failed to bind arguments: expected 2 arguments, got 1",
+		},
+		{
+			name:    "arguments without placeholders",
+			sql:     "SELECT * FROM Users",
+			args:    []interface{}{"Boston"},
+			wantErr: "failed to bind arguments: expected 0 arguments, got 1",
+		},
+		{
+			name:    "missing name",
+			sql:     "SELECT * FROM Users WHERE City = :city",
+			args:    []interface{}{map[string]interface{}{"town": "Boston"}},
+			wantErr: "failed to bind arguments: no value for :city",
+		},
+		{
+			name:    "positional argument for a name",
+			sql:     "SELECT * FROM Users WHERE City = :city",
+			args:    []interface{}{"Boston"},
+			wantErr: "failed to bind arguments: named placeholders take sql.Named arguments or a single map or struct, got string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var users []User
+			err := parser.Query(tt.sql, &users, tt.args...)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Query() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+
+			var ids []int
+			for _, user := range users {
+				ids = append(ids, user.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("Query() IDs = %v, expected %v", ids, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSQLParser_Delete_Params(t *testing.T) {
+	mock := SetupTestData()
+	parser := NewSQLParser(mock.Client("test-id"))
+
+	if err := parser.Delete("DELETE FROM Users WHERE City = ? AND Age < ?", "New York", 29); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if rows := mock.GetSheetData("Users"); len(rows) != 5 {
+		t.Errorf("expected 5 rows including header, got %d", len(rows))
+	}
+
+	err := parser.Delete("DELETE FROM Users WHERE City = ?")
+	if err == nil || err.Error() != "failed to bind arguments: expected 1 arguments, got 0" {
+		t.Errorf("Delete() error = %v, expected a binding error", err)
+	}
+}
+
+func TestParser_Params(t *testing.T) {
+	tests := []struct {
+		sql      string
+		expected placeholders
+		wantErr  string
+	}{
+		{sql: "SELECT * FROM Users WHERE ID = ? OR ID = ?", expected: placeholders{count: 2}},
+		{sql: "SELECT * FROM Users WHERE ID = $3 OR ID = $1", expected: placeholders{count: 3}},
+		{sql: "SELECT * FROM Users WHERE ID = :id OR Name = :name OR ID = :id", expected: placeholders{names: []string{"id", "name"}}},
+		{sql: "SELECT * FROM Users WHERE ID = ? OR ID = $1", wantErr: "line 1, column 42: cannot mix ? and $n placeholders"},
+		{sql: "SELECT * FROM Users WHERE ID = :id OR ID = ?", wantErr: "line 1, column 44: cannot mix :name and ? placeholders"},
+		{sql: "SELECT * FROM Users WHERE ID = $0", wantErr: "line 1, column 32: invalid placeholder $0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			stmt, err := NewSQLParser(&Client{}).parse(tt.sql)
+			if tt.wantErr != "" {
+				var syntaxErr *SyntaxError
+				if !errors.As(err, &syntaxErr) || err.Error() != tt.wantErr {
+					t.Errorf("parse() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			if got := stmt.statementParams(); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("placeholders = %+v, expected %+v", got, tt.expected)
+			}
+			if got := stmt.statementQuery().String(); got != tt.sql {
+				t.Errorf("String() = %q, expected %q", got, tt.sql)
+			}
+		})
+	}
+}
@@ -0,0 +1,1436 @@
+package sheetsql
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statement is a parsed SQL statement. SELECT, UPDATE and DELETE carry the
+// Query they run; INSERT carries the Query for its target sheet.
+type statement interface {
+	statementQuery() *Query
+	statementParams() placeholders
+}
+
+type selectStmt struct {
+	query *Query
+	placeholders
+}
+
+// insertStmt without values or source writes a row struct given when it
+// runs.
+type insertStmt struct {
+	query   *Query
+	columns []string // the column list, if any
+	values  [][]expr // INSERT ... VALUES
+	source  *Query   // INSERT ... SELECT
+	placeholders
+}
+
+type updateStmt struct {
+	query       *Query
+	assignments []assignment
+	placeholders
+}
+
+type deleteStmt struct {
+	query *Query
+	placeholders
+}
+
+type assignment struct {
+	column string
+	value  expr
+}
+
+func (s *selectStmt) statementQuery() *Query { return s.query }
+func (s *insertStmt) statementQuery() *Query { return s.query }
+func (s *updateStmt) statementQuery() *Query { return s.query }
+func (s *deleteStmt) statementQuery() *Query { return s.query }
+
+// reservedWords cannot be used as bare table or column names; quote them
+// with backticks or brackets instead.
+var reservedWords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "AND": true, "OR": true,
+	"NOT": true, "LIMIT": true, "OFFSET": true, "INSERT": true, "INTO": true,
+	"VALUES": true, "UPDATE": true, "SET": true, "DELETE": true, "AS": true,
+	"LIKE": true, "TRUE": true, "FALSE": true, "NULL": true, "ORDER": true,
+	"BY": true, "GROUP": true, "HAVING": true, "DISTINCT": true, "JOIN": true,
+	"INNER": true, "LEFT": true, "OUTER": true, "CROSS": true, "ON": true,
+	"IN": true, "EXISTS": true, "BETWEEN": true, "IS": true, "ILIKE": true,
+	"GLOB": true, "REGEXP": true, "ESCAPE": true, "CONTAINS": true,
+	"CASE": true, "WHEN": true, "THEN": true, "ELSE": true, "END": true,
+	"UNION": true, "INTERSECT": true, "EXCEPT": true, "WITH": true,
+	"OVER": true, "PIVOT": true, "UNPIVOT": true,
+}
+
+type parser struct {
+	tokens    []token
+	pos       int
+	client    *Client
+	functions map[string]*scalarFunction
+
+	// params collects the placeholders of the statement, all written in
+	// paramStyle: "?", "$" or ":".
+	params     placeholders
+	paramStyle string
+
+	// ctes are the common table expressions in scope, by name.
+	ctes map[string]*cte
+}
+
+func newParser(sql string, client *Client) (*parser, error) {
+	tokens, err := tokenize(sql)
+	if err != nil {
+		return nil, err
+	}
+	return &parser{tokens: tokens, client: client}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// peekAt returns the token n positions ahead, or the final tokEOF token.
+func (p *parser) peekAt(n int) token {
+	if p.pos+n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+n]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) errorf(tok token, format string, args ...interface{}) error {
+	return &SyntaxError{Line: tok.pos.line, Column: tok.pos.column, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) unexpected(expected string) error {
+	return p.errorf(p.peek(), "expected %s, found %s", expected, p.peek())
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	tok := p.peek()
+	return tok.kind == tokIdent && !tok.quoted && strings.EqualFold(tok.text, keyword)
+}
+
+func (p *parser) acceptKeyword(keyword string) bool {
+	if p.isKeyword(keyword) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// startsSelect reports whether the token n positions ahead begins a SELECT,
+// possibly with a WITH clause.
+func (p *parser) startsSelect(n int) bool {
+	tok := p.peekAt(n)
+	return tok.kind == tokIdent && !tok.quoted && (strings.EqualFold(tok.text, "SELECT") || strings.EqualFold(tok.text, "WITH"))
+}
+
+func (p *parser) expectKeyword(keyword string) error {
+	if !p.acceptKeyword(keyword) {
+		return p.unexpected(keyword)
+	}
+	return nil
+}
+
+func (p *parser) isOperator(op string) bool {
+	tok := p.peek()
+	return tok.kind == tokOperator && tok.text == op
+}
+
+func (p *parser) acceptOperator(op string) bool {
+	if p.isOperator(op) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectOperator(op string) error {
+	if !p.acceptOperator(op) {
+		return p.unexpected(fmt.Sprintf("%q", op))
+	}
+	return nil
+}
+
+// parseStatement parses a single statement, optionally followed by ";".
+func (p *parser) parseStatement() (statement, error) {
+	var stmt statement
+	var err error
+
+	switch {
+	case p.isKeyword("SELECT") || p.isKeyword("WITH"):
+		var s *selectStmt
+		if s, err = p.parseSelect(); err == nil {
+			s.placeholders = p.params
+			stmt = s
+		}
+	case p.isKeyword("INSERT"):
+		var s *insertStmt
+		if s, err = p.parseInsert(); err == nil {
+			s.placeholders = p.params
+			stmt = s
+		}
+	case p.isKeyword("UPDATE"):
+		var s *updateStmt
+		if s, err = p.parseUpdate(); err == nil {
+			s.placeholders = p.params
+			stmt = s
+		}
+	case p.isKeyword("DELETE"):
+		var s *deleteStmt
+		if s, err = p.parseDelete(); err == nil {
+			s.placeholders = p.params
+			stmt = s
+		}
+	default:
+		return nil, p.unexpected("SELECT, INSERT, UPDATE or DELETE")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.acceptOperator(";")
+	if err := p.expectEOF(); err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+func (p *parser) expectEOF() error {
+	if p.peek().kind != tokEOF {
+		return p.unexpected("end of input")
+	}
+	return nil
+}
+
+// parseSelect reads a SELECT, possibly preceded by a WITH clause and
+// combined with others by set operations, followed by the ORDER BY, LIMIT
+// and OFFSET of the whole.
+func (p *parser) parseSelect() (*selectStmt, error) {
+	var with []*cte
+	if p.isKeyword("WITH") {
+		defer func(scope map[string]*cte) { p.ctes = scope }(p.ctes)
+
+		var err error
+		if with, err = p.parseWith(); err != nil {
+			return nil, err
+		}
+	}
+
+	query, err := p.parseSelectCore()
+	if err != nil {
+		return nil, err
+	}
+	query.with = with
+
+	// INTERSECT binds more tightly than UNION and EXCEPT, so it combines
+	// with the SELECT just before it: the first one, or the right-hand side
+	// of the last UNION or EXCEPT.
+	operand := query
+	for {
+		op := setOp{op: strings.ToUpper(p.peek().text)}
+		if !p.acceptKeyword("UNION") && !p.acceptKeyword("INTERSECT") && !p.acceptKeyword("EXCEPT") {
+			break
+		}
+		op.all = p.acceptKeyword("ALL")
+		if !op.all {
+			p.acceptKeyword("DISTINCT")
+		}
+		if op.query, err = p.parseSelectCore(); err != nil {
+			return nil, err
+		}
+		if op.op == "INTERSECT" {
+			operand.setOps = append(operand.setOps, op)
+			continue
+		}
+		query.setOps = append(query.setOps, op)
+		operand = op.query
+	}
+
+	if p.acceptKeyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		if query.orderBy, err = p.parseOrderItems(); err != nil {
+			return nil, err
+		}
+	}
+
+	// LIMIT and OFFSET may come in either order, each at most once.
+	var hasLimit, hasOffset bool
+	for {
+		switch {
+		case !hasLimit && p.acceptKeyword("LIMIT"):
+			limit, err := p.parseCount("LIMIT")
+			if err != nil {
+				return nil, err
+			}
+			query.Limit(limit)
+			hasLimit = true
+			continue
+		case !hasOffset && p.acceptKeyword("OFFSET"):
+			offset, err := p.parseCount("OFFSET")
+			if err != nil {
+				return nil, err
+			}
+			query.Offset(offset)
+			hasOffset = true
+			continue
+		}
+		break
+	}
+
+	return &selectStmt{query: query}, nil
+}
+
+// parseSelectCore reads a single SELECT up to its HAVING clause.
+func (p *parser) parseSelectCore() (*Query, error) {
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+
+	distinct := p.acceptKeyword("DISTINCT")
+	columns, err := p.parseSelectList()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+
+	query, err := p.parseTable()
+	if err != nil {
+		return nil, err
+	}
+	query.columns = columns
+	query.distinct = distinct
+
+	if query.pivot, err = p.parsePivot(); err != nil {
+		return nil, err
+	}
+	if query.alias, err = p.parseAlias(); err != nil {
+		return nil, err
+	}
+	if err := p.parseJoins(query); err != nil {
+		return nil, err
+	}
+
+	if p.acceptKeyword("WHERE") {
+		if err := p.parseWhere(query); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.acceptKeyword("GROUP") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			query.groupBy = append(query.groupBy, e)
+			if !p.acceptOperator(",") {
+				break
+			}
+		}
+	}
+
+	if p.acceptKeyword("HAVING") {
+		cond, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		query.having = cond
+	}
+
+	return query, nil
+}
+
+// parsePivot reads an optional "PIVOT (agg FOR column IN (value [AS name],
+// ...))" or "UNPIVOT (value FOR column IN (column, ...))" after the table a
+// query is on.
+func (p *parser) parsePivot() (*pivotClause, error) {
+	pc := &pivotClause{}
+	switch {
+	case p.acceptKeyword("PIVOT"):
+	case p.acceptKeyword("UNPIVOT"):
+		pc.unpivot = true
+	default:
+		return nil, nil
+	}
+	if err := p.expectOperator("("); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if pc.unpivot {
+		if pc.value, err = p.parseIdentifier("column name"); err != nil {
+			return nil, err
+		}
+	} else {
+		tok := p.peek()
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		agg, ok := e.(*aggregateExpr)
+		if !ok {
+			return nil, p.errorf(tok, "PIVOT needs an aggregate function, not %s", e)
+		}
+		pc.agg = agg
+	}
+
+	if err := p.expectKeyword("FOR"); err != nil {
+		return nil, err
+	}
+	if pc.column, err = p.parseIdentifier("column name"); err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("IN"); err != nil {
+		return nil, err
+	}
+
+	if pc.unpivot {
+		columns, err := p.parseColumnList()
+		if err != nil {
+			return nil, err
+		}
+		for _, column := range columns {
+			pc.in = append(pc.in, pivotColumn{name: column})
+		}
+		return pc, p.expectOperator(")")
+	}
+
+	if err := p.expectOperator("("); err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		value, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			return nil, p.errorf(tok, "PIVOT values cannot be NULL")
+		}
+
+		c := pivotColumn{value: value, name: formatValue(value)}
+		alias, err := p.parseAlias()
+		if err != nil {
+			return nil, err
+		}
+		if alias != "" {
+			c.name = alias
+		}
+		if slices.ContainsFunc(pc.in, func(other pivotColumn) bool { return other.name == c.name }) {
+			return nil, p.errorf(tok, "column %s specified more than once", c.name)
+		}
+		pc.in = append(pc.in, c)
+
+		if !p.acceptOperator(",") {
+			break
+		}
+	}
+	if err := p.expectOperator(")"); err != nil {
+		return nil, err
+	}
+	return pc, p.expectOperator(")")
+}
+
+// parseJoins reads any JOIN clauses following the first table. A comma
+// between tables is a CROSS JOIN.
+func (p *parser) parseJoins(query *Query) error {
+	for {
+		var kind string
+		needJoin := true
+		switch {
+		case p.acceptKeyword("JOIN"):
+			kind, needJoin = "INNER", false
+		case p.acceptOperator(","):
+			kind, needJoin = "CROSS", false
+		case p.acceptKeyword("INNER"):
+			kind = "INNER"
+		case p.acceptKeyword("LEFT"):
+			kind = "LEFT"
+			p.acceptKeyword("OUTER")
+		case p.acceptKeyword("CROSS"):
+			kind = "CROSS"
+		default:
+			return nil
+		}
+		if needJoin {
+			if err := p.expectKeyword("JOIN"); err != nil {
+				return err
+			}
+		}
+
+		sheet, err := p.parseIdentifier("sheet name")
+		if err != nil {
+			return err
+		}
+		j := joinClause{kind: kind, sheet: sheet, cte: p.ctes[sheet]}
+		if j.alias, err = p.parseAlias(); err != nil {
+			return err
+		}
+
+		if kind != "CROSS" {
+			if err := p.expectKeyword("ON"); err != nil {
+				return err
+			}
+			if j.on, err = p.parseExpr(); err != nil {
+				return err
+			}
+		}
+
+		query.joins = append(query.joins, j)
+	}
+}
+
+// parseOrderItems reads the sort keys following ORDER BY.
+func (p *parser) parseOrderItems() ([]orderItem, error) {
+	var items []orderItem
+	for {
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		item := orderItem{expr: e}
+		if p.acceptKeyword("DESC") {
+			item.desc = true
+		} else {
+			p.acceptKeyword("ASC")
+		}
+		item.nullsFirst = item.desc
+
+		if p.acceptKeyword("NULLS") {
+			switch {
+			case p.acceptKeyword("FIRST"):
+				item.nullsFirst = true
+			case p.acceptKeyword("LAST"):
+				item.nullsFirst = false
+			default:
+				return nil, p.unexpected("FIRST or LAST")
+			}
+		}
+
+		items = append(items, item)
+		if !p.acceptOperator(",") {
+			return items, nil
+		}
+	}
+}
+
+// parseSelectList reads the items between SELECT and FROM. A lone * selects
+// every column and is returned as an empty list.
+func (p *parser) parseSelectList() ([]selectItem, error) {
+	var items []selectItem
+	for {
+		if p.acceptOperator("*") {
+			items = append(items, selectItem{star: true})
+		} else {
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+
+			alias, err := p.parseAlias()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, selectItem{expr: e, alias: alias})
+		}
+
+		if !p.acceptOperator(",") {
+			break
+		}
+	}
+
+	if len(items) == 1 && items[0].star {
+		return nil, nil
+	}
+	return items, nil
+}
+
+// parseAlias reads an optional "[AS] alias". Aliases may also be written as
+// string literals so that they can contain spaces.
+func (p *parser) parseAlias() (string, error) {
+	explicit := p.acceptKeyword("AS")
+
+	tok := p.peek()
+	switch {
+	case tok.kind == tokString:
+		p.next()
+		return tok.text, nil
+	case tok.kind == tokIdent && (tok.quoted || !reservedWords[strings.ToUpper(tok.text)]):
+		p.next()
+		return tok.text, nil
+	case explicit:
+		return "", p.unexpected("alias")
+	}
+	return "", nil
+}
+
+func (p *parser) parseInsert() (*insertStmt, error) {
+	if err := p.expectKeyword("INSERT"); err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("INTO"); err != nil {
+		return nil, err
+	}
+
+	query, err := p.parseTable()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &insertStmt{query: query}
+	if p.isOperator("(") {
+		if stmt.columns, err = p.parseColumnList(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case p.isKeyword("VALUES"):
+		p.next()
+		for {
+			tok := p.peek()
+			if err := p.expectOperator("("); err != nil {
+				return nil, err
+			}
+			var row []expr
+			for {
+				value, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				row = append(row, value)
+				if !p.acceptOperator(",") {
+					break
+				}
+			}
+			if err := p.expectOperator(")"); err != nil {
+				return nil, err
+			}
+			if stmt.columns != nil && len(row) != len(stmt.columns) {
+				return nil, p.errorf(tok, "VALUES has %d values for %d columns", len(row), len(stmt.columns))
+			}
+			stmt.values = append(stmt.values, row)
+			if !p.acceptOperator(",") {
+				break
+			}
+		}
+	case p.isKeyword("SELECT") || p.isKeyword("WITH"):
+		sel, err := p.parseSelect()
+		if err != nil {
+			return nil, err
+		}
+		stmt.source = sel.query
+	case stmt.columns != nil:
+		return nil, p.unexpected("VALUES or SELECT")
+	}
+
+	return stmt, nil
+}
+
+// parseColumnList reads a parenthesised list of distinct column names.
+func (p *parser) parseColumnList() ([]string, error) {
+	if err := p.expectOperator("("); err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	for {
+		tok := p.peek()
+		column, err := p.parseIdentifier("column name")
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(columns, column) {
+			return nil, p.errorf(tok, "column %s specified more than once", quoteIdentifier(column))
+		}
+		columns = append(columns, column)
+		if !p.acceptOperator(",") {
+			break
+		}
+	}
+
+	if err := p.expectOperator(")"); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+func (p *parser) parseUpdate() (*updateStmt, error) {
+	if err := p.expectKeyword("UPDATE"); err != nil {
+		return nil, err
+	}
+
+	query, err := p.parseTable()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expectKeyword("SET"); err != nil {
+		return nil, err
+	}
+
+	stmt := &updateStmt{query: query}
+	for {
+		column, err := p.parseIdentifier("column name")
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectOperator("="); err != nil {
+			return nil, err
+		}
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		stmt.assignments = append(stmt.assignments, assignment{column: column, value: value})
+
+		if !p.acceptOperator(",") {
+			break
+		}
+	}
+
+	if p.acceptKeyword("WHERE") {
+		if err := p.parseWhere(query); err != nil {
+			return nil, err
+		}
+	}
+
+	return stmt, nil
+}
+
+func (p *parser) parseDelete() (*deleteStmt, error) {
+	if err := p.expectKeyword("DELETE"); err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+
+	query, err := p.parseTable()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.acceptKeyword("WHERE") {
+		if err := p.parseWhere(query); err != nil {
+			return nil, err
+		}
+	}
+
+	return &deleteStmt{query: query}, nil
+}
+
+func (p *parser) parseTable() (*Query, error) {
+	name, err := p.parseIdentifier("sheet name")
+	if err != nil {
+		return nil, err
+	}
+
+	query := p.client.From(name)
+	query.cte = p.ctes[name]
+	return query, nil
+}
+
+// parseIdentifier reads a table, column or alias name. Reserved words must be
+// quoted to be used as names.
+func (p *parser) parseIdentifier(what string) (string, error) {
+	tok := p.peek()
+	if tok.kind != tokIdent || !tok.quoted && reservedWords[strings.ToUpper(tok.text)] {
+		return "", p.unexpected(what)
+	}
+	p.next()
+	return tok.text, nil
+}
+
+// parseWhere reads a WHERE condition and adds it to query.
+func (p *parser) parseWhere(query *Query) error {
+	cond, err := p.parseExpr()
+	if err != nil {
+		return err
+	}
+
+	query.where = and(query.where, cond)
+	return nil
+}
+
+// parseExpr reads an expression. From lowest to highest precedence the
+// levels are OR, AND, NOT, comparisons and operands.
+func (p *parser) parseExpr() (expr, error) {
+	return p.parseOr()
+}
+
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpr{op: "OR", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpr{op: "AND", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseNot() (expr, error) {
+	if p.acceptKeyword("NOT") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{operand: operand}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (expr, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.acceptKeyword("IS") {
+		not := p.acceptKeyword("NOT")
+		if err := p.expectKeyword("NULL"); err != nil {
+			return nil, err
+		}
+		return &isNullExpr{operand: left, not: not}, nil
+	}
+
+	not := p.acceptKeyword("NOT")
+	switch {
+	case p.acceptKeyword("IN"):
+		return p.parseIn(left, not)
+	case p.acceptKeyword("BETWEEN"):
+		return p.parseBetween(left, not)
+	}
+
+	operator, ok := p.acceptComparison()
+	switch {
+	case !ok && not:
+		return nil, p.unexpected("IN, BETWEEN, LIKE, ILIKE, GLOB, REGEXP or CONTAINS")
+	case !ok:
+		return left, nil
+	case not && builtinOperators["NOT "+operator] == nil && p.client.customOperator(operator) == nil:
+		return nil, p.errorf(p.tokens[p.pos-1], "NOT cannot be used with %s", operator)
+	case not:
+		operator = "NOT " + operator
+	}
+
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &comparison{op: operator, left: left, right: right}
+	if p.acceptKeyword("ESCAPE") {
+		if op := strings.TrimPrefix(operator, "NOT "); op != "LIKE" && op != "ILIKE" {
+			return nil, p.errorf(p.tokens[p.pos-1], "ESCAPE can only be used with LIKE or ILIKE")
+		}
+		if c.escape, err = p.parseOperand(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (p *parser) acceptComparison() (string, bool) {
+	for _, keyword := range []string{"LIKE", "ILIKE", "GLOB", "REGEXP", "CONTAINS"} {
+		if p.acceptKeyword(keyword) {
+			return keyword, true
+		}
+	}
+
+	tok := p.peek()
+	if tok.kind == tokIdent && !tok.quoted && p.client.customOperator(strings.ToUpper(tok.text)) != nil {
+		p.next()
+		return strings.ToUpper(tok.text), true
+	}
+	if tok.kind == tokOperator {
+		switch tok.text {
+		case "=", "==", "!=", "<", ">", "<=", ">=", "~", "!~":
+			p.next()
+			return tok.text, true
+		case "<>":
+			p.next()
+			return "!=", true
+		}
+	}
+
+	return "", false
+}
+
+// parseSum reads operands joined by +, - and ||.
+func (p *parser) parseSum() (expr, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator("+") || p.isOperator("-") || p.isOperator("||") {
+		op := p.next().text
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parseProduct reads operands joined by *, / and %.
+func (p *parser) parseProduct() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator("*") || p.isOperator("/") || p.isOperator("%") {
+		op := p.next().text
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parseUnary reads an operand with an optional minus sign. A minus before a
+// number is part of the literal.
+func (p *parser) parseUnary() (expr, error) {
+	if p.isOperator("-") && p.peekAt(1).kind != tokNumber {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negExpr{operand: operand}, nil
+	}
+
+	return p.parseOperand()
+}
+
+// parseOperand reads a parenthesised expression, a column name, a function
+// call, a CASE expression or a literal.
+func (p *parser) parseOperand() (expr, error) {
+	if p.acceptKeyword("CASE") {
+		return p.parseCase()
+	}
+
+	if p.acceptKeyword("EXISTS") {
+		query, err := p.parseSubquery()
+		if err != nil {
+			return nil, err
+		}
+		return &existsExpr{query: query}, nil
+	}
+
+	if p.isOperator("(") && p.startsSelect(1) {
+		query, err := p.parseSubquery()
+		if err != nil {
+			return nil, err
+		}
+		return &subqueryExpr{query: query}, nil
+	}
+
+	if p.acceptOperator("(") {
+		inner, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectOperator(")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	}
+
+	if tok := p.peek(); tok.kind == tokIdent && (tok.quoted || !reservedWords[strings.ToUpper(tok.text)]) {
+		p.next()
+		if !tok.quoted && p.peek().kind == tokString && (strings.EqualFold(tok.text, "DATE") || strings.EqualFold(tok.text, "TIMESTAMP")) {
+			return p.parseTypedLiteral(tok)
+		}
+		if !tok.quoted && p.acceptOperator("(") {
+			return p.parseCall(tok)
+		}
+		if p.acceptOperator(".") {
+			name, err := p.parseIdentifier("column name")
+			if err != nil {
+				return nil, err
+			}
+			return &columnRef{table: tok.text, name: name}, nil
+		}
+		return &columnRef{name: tok.text}, nil
+	}
+
+	if tok := p.peek(); tok.kind == tokParam {
+		p.next()
+		return p.addParam(tok)
+	}
+
+	value, err := p.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+	return &literal{value: value}, nil
+}
+
+// parseIn reads the parenthesised value list or subquery following IN.
+func (p *parser) parseIn(operand expr, not bool) (expr, error) {
+	in := &inExpr{operand: operand, not: not}
+
+	if p.startsSelect(1) {
+		query, err := p.parseSubquery()
+		if err != nil {
+			return nil, err
+		}
+		in.query = query
+		return in, nil
+	}
+
+	if err := p.expectOperator("("); err != nil {
+		return nil, err
+	}
+	for {
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		in.values = append(in.values, value)
+		if !p.acceptOperator(",") {
+			break
+		}
+	}
+	if err := p.expectOperator(")"); err != nil {
+		return nil, err
+	}
+
+	return in, nil
+}
+
+// parseBetween reads "low AND high". The bounds are arithmetic expressions,
+// not conditions, so that the AND is not taken for a logical operator.
+func (p *parser) parseBetween(operand expr, not bool) (expr, error) {
+	low, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("AND"); err != nil {
+		return nil, err
+	}
+	high, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	return &betweenExpr{operand: operand, low: low, high: high, not: not}, nil
+}
+
+// parseSubquery reads a parenthesised SELECT statement.
+func (p *parser) parseSubquery() (*Query, error) {
+	if err := p.expectOperator("("); err != nil {
+		return nil, err
+	}
+
+	sel, err := p.parseSelect()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expectOperator(")"); err != nil {
+		return nil, err
+	}
+	return sel.query, nil
+}
+
+// parseCall reads the arguments of a function call once name and the opening
+// parenthesis have been consumed.
+func (p *parser) parseCall(name token) (expr, error) {
+	fn := strings.ToUpper(name.text)
+	switch fn {
+	case "CAST":
+		return p.parseCast()
+	case "EXTRACT":
+		return p.parseExtract()
+	}
+	if scalar, ok := scalarFunctions[fn]; ok {
+		return p.parseScalarCall(name, fn, scalar)
+	}
+	if scalar, ok := p.functions[fn]; ok {
+		return p.parseScalarCall(name, fn, scalar)
+	}
+	if scalar, ok := windowFunctions[fn]; ok {
+		call, err := p.parseScalarCall(name, fn, scalar)
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptKeyword("OVER") {
+			return nil, p.errorf(name, "%s requires an OVER clause", fn)
+		}
+		return p.parseOver(&windowExpr{fn: fn, args: call.(*funcCall).args})
+	}
+	if !aggregateFunctions[fn] {
+		return nil, p.errorf(name, "unknown function %s", name.text)
+	}
+
+	agg := &aggregateExpr{fn: fn}
+	if fn != "COUNT" || !p.acceptOperator("*") {
+		agg.distinct = p.acceptKeyword("DISTINCT")
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		agg.arg = arg
+	}
+	if err := p.expectOperator(")"); err != nil {
+		return nil, err
+	}
+
+	if p.acceptKeyword("OVER") {
+		return p.parseOver(&windowExpr{fn: fn, agg: agg})
+	}
+	return agg, nil
+}
+
+// parseOver reads the window of a window function after OVER:
+// "([PARTITION BY exprs] [ORDER BY keys] [ROWS frame])".
+func (p *parser) parseOver(w *windowExpr) (expr, error) {
+	if err := p.expectOperator("("); err != nil {
+		return nil, err
+	}
+
+	if p.acceptKeyword("PARTITION") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			w.partitionBy = append(w.partitionBy, e)
+			if !p.acceptOperator(",") {
+				break
+			}
+		}
+	}
+
+	if p.acceptKeyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		var err error
+		if w.orderBy, err = p.parseOrderItems(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.acceptKeyword("ROWS") {
+		frame, err := p.parseFrame()
+		if err != nil {
+			return nil, err
+		}
+		w.frame = frame
+	}
+
+	return w, p.expectOperator(")")
+}
+
+// parseFrame reads the frame following ROWS, either "BETWEEN start AND end"
+// or a start alone, which ends at the current row.
+func (p *parser) parseFrame() (*windowFrame, error) {
+	tok := p.peek()
+	frame := &windowFrame{}
+	var err error
+	if p.acceptKeyword("BETWEEN") {
+		if frame.start, err = p.parseFrameBound(); err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("AND"); err != nil {
+			return nil, err
+		}
+		if frame.end, err = p.parseFrameBound(); err != nil {
+			return nil, err
+		}
+	} else if frame.start, err = p.parseFrameBound(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case frame.start == unboundedFrame:
+		return nil, p.errorf(tok, "a frame cannot start at UNBOUNDED FOLLOWING")
+	case frame.end == -unboundedFrame:
+		return nil, p.errorf(tok, "a frame cannot end at UNBOUNDED PRECEDING")
+	case frame.start > frame.end:
+		return nil, p.errorf(tok, "frame starts after it ends")
+	}
+	return frame, nil
+}
+
+// parseFrameBound reads UNBOUNDED PRECEDING, n PRECEDING, CURRENT ROW,
+// n FOLLOWING or UNBOUNDED FOLLOWING and returns it as an offset from the
+// current row.
+func (p *parser) parseFrameBound() (int, error) {
+	if p.acceptKeyword("CURRENT") {
+		return 0, p.expectKeyword("ROW")
+	}
+
+	n := unboundedFrame
+	if !p.acceptKeyword("UNBOUNDED") {
+		var err error
+		if n, err = p.parseCount("frame"); err != nil {
+			return 0, err
+		}
+		n = min(n, unboundedFrame-1)
+	}
+
+	switch {
+	case p.acceptKeyword("PRECEDING"):
+		return -n, nil
+	case p.acceptKeyword("FOLLOWING"):
+		return n, nil
+	}
+	return 0, p.unexpected("PRECEDING or FOLLOWING")
+}
+
+// parseScalarCall reads the arguments of a scalar function and checks their
+// number.
+func (p *parser) parseScalarCall(name token, fn string, scalar *scalarFunction) (expr, error) {
+	call := &funcCall{name: fn, fn: scalar}
+	if !p.isOperator(")") {
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if !p.acceptOperator(",") {
+				break
+			}
+		}
+	}
+	if err := p.expectOperator(")"); err != nil {
+		return nil, err
+	}
+
+	n := len(call.args)
+	if n < scalar.minArgs || scalar.maxArgs >= 0 && n > scalar.maxArgs {
+		var want string
+		switch {
+		case scalar.maxArgs < 0:
+			want = fmt.Sprintf("at least %d", scalar.minArgs)
+		case scalar.minArgs == scalar.maxArgs:
+			want = fmt.Sprint(scalar.minArgs)
+		default:
+			want = fmt.Sprintf("%d to %d", scalar.minArgs, scalar.maxArgs)
+		}
+		return nil, p.errorf(name, "%s takes %s arguments, not %d", fn, want, n)
+	}
+
+	return call, nil
+}
+
+// parseCast reads "x AS type)" after CAST(.
+func (p *parser) parseCast() (expr, error) {
+	operand, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("AS"); err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	typ, ok := castTypes[strings.ToUpper(tok.text)]
+	if tok.kind != tokIdent || !ok {
+		return nil, p.unexpected("INT, FLOAT, TEXT, DATE or TIMESTAMP")
+	}
+	p.next()
+
+	return &castExpr{operand: operand, typ: typ}, p.expectOperator(")")
+}
+
+// parseExtract reads "unit FROM x)" after EXTRACT(.
+func (p *parser) parseExtract() (expr, error) {
+	tok := p.peek()
+	unit := strings.ToUpper(tok.text)
+	if tok.kind != tokIdent || !dateUnits[unit] && !extractUnits[unit] {
+		return nil, p.unexpected("date part")
+	}
+	p.next()
+
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	operand, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	return &extractExpr{unit: unit, operand: operand}, p.expectOperator(")")
+}
+
+// parseTypedLiteral reads the string of a DATE '2024-03-01' or TIMESTAMP
+// '2024-03-01 12:00:00' literal once the type name has been consumed.
+func (p *parser) parseTypedLiteral(typ token) (expr, error) {
+	tok := p.next()
+	layouts := []string{dateLayout}
+	if strings.EqualFold(typ.text, "TIMESTAMP") {
+		layouts = isoLayouts
+	}
+
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, tok.text); err == nil {
+			return &literal{value: t.UTC()}, nil
+		}
+	}
+	return nil, p.errorf(tok, "invalid %s literal '%s'", strings.ToUpper(typ.text), tok.text)
+}
+
+// parseCase reads the rest of a CASE expression once CASE has been consumed.
+func (p *parser) parseCase() (expr, error) {
+	c := &caseExpr{}
+	if !p.isKeyword("WHEN") && !p.isKeyword("ELSE") && !p.isKeyword("END") {
+		operand, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		c.operand = operand
+	}
+
+	for p.acceptKeyword("WHEN") {
+		when, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("THEN"); err != nil {
+			return nil, err
+		}
+		then, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		c.whens = append(c.whens, whenClause{when: when, then: then})
+	}
+	if len(c.whens) == 0 {
+		return nil, p.unexpected("WHEN")
+	}
+
+	if p.acceptKeyword("ELSE") {
+		els, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		c.els = els
+	}
+
+	return c, p.expectKeyword("END")
+}
+
+// parseLiteral reads a number, string, TRUE, FALSE or NULL. Integers become
+// int and other numbers float64.
+func (p *parser) parseLiteral() (interface{}, error) {
+	switch {
+	case p.acceptKeyword("TRUE"):
+		return true, nil
+	case p.acceptKeyword("FALSE"):
+		return false, nil
+	case p.acceptKeyword("NULL"):
+		return nil, nil
+	}
+
+	tok := p.peek()
+	negative := false
+	if tok.kind == tokOperator && tok.text == "-" {
+		p.next()
+		negative = true
+		if p.peek().kind != tokNumber {
+			return nil, p.unexpected("number")
+		}
+	}
+
+	tok = p.peek()
+	switch tok.kind {
+	case tokString:
+		p.next()
+		return tok.text, nil
+	case tokNumber:
+		p.next()
+		text := tok.text
+		if negative {
+			text = "-" + text
+		}
+		if intVal, err := strconv.Atoi(text); err == nil {
+			return intVal, nil
+		}
+		floatVal, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, p.errorf(tok, "invalid number %s", tok.text)
+		}
+		return floatVal, nil
+	}
+
+	return nil, p.unexpected("value")
+}
+
+func (p *parser) parseCount(clause string) (int, error) {
+	tok := p.peek()
+	if tok.kind != tokNumber {
+		return 0, p.unexpected(fmt.Sprintf("%s value", clause))
+	}
+	p.next()
+
+	n, err := strconv.Atoi(tok.text)
+	if err != nil || n < 0 {
+		return 0, p.errorf(tok, "invalid %s value %s", clause, tok.text)
+	}
+	return n, nil
+}
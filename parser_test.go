@@ -0,0 +1,136 @@
+package sheetsql
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParser_parseStatement(t *testing.T) {
+	client := &Client{}
+
+	tests := []struct {
+		name     string
+		sql      string
+		table    string
+		expected string
+	}{
+		{
+			name:     "quoted column with spaces",
+			sql:      "SELECT * FROM Users WHERE `First Name` = 'John'",
+			table:    "Users",
+			expected: "`First Name` = 'John'",
+		},
+		{
+			name:     "AND inside a string literal",
+			sql:      "SELECT * FROM Users WHERE Company = 'Smith and Sons' AND Age > 30",
+			table:    "Users",
+			expected: "Company = 'Smith and Sons' AND Age > 30",
+		},
+		{
+			name:     "equals sign inside a value",
+			sql:      "DELETE FROM [Sales Data] WHERE Formula = 'a=b'",
+			table:    "Sales Data",
+			expected: "Formula = 'a=b'",
+		},
+		{
+			name:     "negative number and trailing semicolon",
+			sql:      "UPDATE Accounts SET Balance = 0 WHERE Balance < -10.5;",
+			table:    "Accounts",
+			expected: "Balance < -10.5",
+		},
+		{
+			name:     "multi-line statement",
+			sql:      "SELECT *\nFROM Users\nWHERE Active = false\nLIMIT 5",
+			table:    "Users",
+			expected: "Active = FALSE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newParser(tt.sql, client)
+			if err != nil {
+				t.Fatalf("newParser() error = %v", err)
+			}
+
+			stmt, err := p.parseStatement()
+			if err != nil {
+				t.Fatalf("parseStatement() error = %v", err)
+			}
+
+			query := stmt.statementQuery()
+			if query.sheetName != tt.table {
+				t.Errorf("table = %q, expected %q", query.sheetName, tt.table)
+			}
+			if query.where.String() != tt.expected {
+				t.Errorf("where = %s, expected %s", query.where, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParser_SyntaxErrors(t *testing.T) {
+	client := &Client{}
+
+	tests := []struct {
+		name   string
+		sql    string
+		line   int
+		column int
+		msg    string
+	}{
+		{"missing FROM", "SELECT * Users", 1, 10, `expected FROM, found "Users"`},
+		{"missing value", "SELECT * FROM Users WHERE Age >", 1, 32, "expected value, found end of input"},
+		{"bad operator", "SELECT * FROM Users WHERE Age ! 3", 1, 31, `unexpected character '!'`},
+		{"reserved word as table", "SELECT * FROM WHERE", 1, 15, `expected sheet name, found "WHERE"`},
+		{"trailing tokens", "DELETE FROM Users\nWHERE Age > 3 Name", 2, 15, `expected end of input, found "Name"`},
+		{"unclosed parenthesis", "SELECT * FROM Users WHERE (Age > 3 OR Age < 1", 1, 46, `expected ")", found end of input`},
+		{"missing BY", "SELECT * FROM Users ORDER Age", 1, 27, `expected BY, found "Age"`},
+		{"bad NULLS", "SELECT * FROM Users ORDER BY Age NULLS LOW", 1, 40, `expected FIRST or LAST, found "LOW"`},
+		{"join without ON", "SELECT * FROM Orders JOIN Users WHERE Total > 1", 1, 33, `expected ON, found "WHERE"`},
+		{"NOT without operator", "SELECT * FROM Users WHERE Age NOT 5", 1, 35, `expected IN, BETWEEN, LIKE, ILIKE, GLOB, REGEXP or CONTAINS, found "5"`},
+		{"NOT with comparison", "SELECT * FROM Users WHERE Age NOT = 5", 1, 35, "NOT cannot be used with ="},
+		{"ESCAPE with GLOB", "SELECT * FROM Users WHERE Name GLOB 'a*' ESCAPE '!'", 1, 42, "ESCAPE can only be used with LIKE or ILIKE"},
+		{"IS without NULL", "SELECT * FROM Users WHERE Age IS 5", 1, 34, `expected NULL, found "5"`},
+		{"BETWEEN without AND", "SELECT * FROM Users WHERE Age BETWEEN 1 OR 5", 1, 41, `expected AND, found "OR"`},
+		{"wrong argument count", "SELECT SUBSTR(Name) FROM Users", 1, 8, "SUBSTR takes 2 to 3 arguments, not 1"},
+		{"bad cast type", "SELECT CAST(Age AS MONEY) FROM Users", 1, 20, `expected INT, FLOAT, TEXT, DATE or TIMESTAMP, found "MONEY"`},
+		{"bad date literal", "SELECT * FROM Users WHERE Joined > DATE '2024-13-01'", 1, 41, "invalid DATE literal '2024-13-01'"},
+		{"bad date part", "SELECT EXTRACT(CENTURY FROM Joined) FROM Users", 1, 16, `expected date part, found "CENTURY"`},
+		{"CASE without WHEN", "SELECT CASE ELSE 1 END FROM Users", 1, 13, `expected WHEN, found "ELSE"`},
+		{"CASE without END", "SELECT CASE WHEN Age > 1 THEN 1 FROM Users", 1, 33, `expected END, found "FROM"`},
+		{"unknown function", "SELECT MEDIAN(Age) FROM Users", 1, 8, "unknown function MEDIAN"},
+		{"bad limit", "SELECT * FROM Users LIMIT 'ten'", 1, 27, "expected LIMIT value, found string 'ten'"},
+		{"duplicate INSERT column", "INSERT INTO Users (Name, Age, Name) VALUES (1, 2, 3)", 1, 31, "column Name specified more than once"},
+		{"column list without rows", "INSERT INTO Users (Name)", 1, 25, "expected VALUES or SELECT, found end of input"},
+		{"UNION without SELECT", "SELECT * FROM Jan UNION Feb", 1, 25, `expected SELECT, found "Feb"`},
+		{"duplicate WITH query", "WITH t AS (SELECT * FROM Users), t AS (SELECT * FROM Users) SELECT * FROM t", 1, 34, "WITH query t specified more than once"},
+		{"WITH without parenthesis", "WITH t AS SELECT * FROM Users", 1, 11, `expected "(", found "SELECT"`},
+		{"recursive anchor", "WITH RECURSIVE r AS (SELECT * FROM r UNION SELECT * FROM Users) SELECT * FROM r", 1, 16, "recursive query r must start with a SELECT that does not read it"},
+		{"recursive EXCEPT", "WITH RECURSIVE r AS (SELECT ID FROM Users EXCEPT SELECT ID FROM r) SELECT * FROM r", 1, 16, "recursive query r must end with the SELECTs that read it, joined by UNION [ALL]"},
+		{"window without OVER", "SELECT RANK() FROM Users", 1, 8, "RANK requires an OVER clause"},
+		{"window arguments", "SELECT LAG() OVER () FROM Users", 1, 8, "LAG takes 1 to 3 arguments, not 0"},
+		{"frame bound", "SELECT SUM(Age) OVER (ROWS 2 BEFORE) FROM Users", 1, 30, `expected PRECEDING or FOLLOWING, found "BEFORE"`},
+		{"backwards frame", "SELECT SUM(Age) OVER (ROWS BETWEEN 1 FOLLOWING AND CURRENT ROW) FROM Users", 1, 28, "frame starts after it ends"},
+		{"frame from the end", "SELECT SUM(Age) OVER (ROWS UNBOUNDED FOLLOWING) FROM Users", 1, 28, "a frame cannot start at UNBOUNDED FOLLOWING"},
+		{"PIVOT without aggregate", "SELECT * FROM Sales PIVOT (Amount FOR Month IN ('Jan'))", 1, 28, "PIVOT needs an aggregate function, not Amount"},
+		{"PIVOT NULL value", "SELECT * FROM Sales PIVOT (SUM(Amount) FOR Month IN ('Jan', NULL))", 1, 61, "PIVOT values cannot be NULL"},
+		{"duplicate PIVOT column", "SELECT * FROM Sales PIVOT (SUM(Amount) FOR Month IN ('Jan', 'Feb' AS Jan))", 1, 61, "column Jan specified more than once"},
+		{"UNPIVOT without IN", "SELECT * FROM Budget UNPIVOT (Amount FOR Month (Jan))", 1, 48, `expected IN, found "("`},
+		{"unknown statement", "DROP TABLE Users", 1, 1, `expected SELECT, INSERT, UPDATE or DELETE, found "DROP"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSQLParser(client).parse(tt.sql)
+
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("parse() error = %v, expected *SyntaxError", err)
+			}
+			if syntaxErr.Line != tt.line || syntaxErr.Column != tt.column || syntaxErr.Msg != tt.msg {
+				t.Errorf("parse() error = %v, expected line %d, column %d: %s", syntaxErr, tt.line, tt.column, tt.msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package sheetsql
+
+import (
+	"container/list"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// patternOperators are the operators that match a value against a pattern.
+// Each may also be written with NOT in front.
+var patternOperators = map[string]bool{
+	"LIKE": true, "ILIKE": true, "GLOB": true, "REGEXP": true,
+}
+
+// defaultEscape escapes % and _ in LIKE patterns that have no ESCAPE clause.
+const defaultEscape = `\`
+
+// matchPattern reports whether value matches pattern under a pattern
+// operator or its NOT form. ~ and !~ are REGEXP and NOT REGEXP. escape is
+// the LIKE escape character, or empty for none.
+func (env *evalEnv) matchPattern(operator, value, pattern, escape string) (bool, error) {
+	switch operator {
+	case "~":
+		operator = "REGEXP"
+	case "!~":
+		operator = "NOT REGEXP"
+	}
+	op, negated := strings.CutPrefix(operator, "NOT ")
+
+	re, err := env.query.patterns.compile(op, pattern, escape)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(value) != negated, nil
+}
+
+// maxCachedPatterns is how many compiled patterns a query keeps, so that
+// patterns bound to placeholders or read from cells do not pile up on a
+// prepared statement.
+const maxCachedPatterns = 64
+
+// patternCache is a least-recently-used cache of compiled patterns. It lives
+// on the query, so a pattern is compiled once however many rows and runs it
+// is matched in, and is safe for runs in several goroutines at once.
+type patternCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   list.List // most recently used first
+}
+
+type patternEntry struct {
+	key string
+	re  *regexp.Regexp
+}
+
+// compile returns the regular expression of a pattern, compiling it unless
+// it is cached.
+func (c *patternCache) compile(op, pattern, escape string) (*regexp.Regexp, error) {
+	key := op + "\x00" + escape + "\x00" + pattern
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*patternEntry).re, nil
+	}
+
+	re, err := compilePattern(op, pattern, escape)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+	}
+	c.entries[key] = c.order.PushFront(&patternEntry{key: key, re: re})
+	if c.order.Len() > maxCachedPatterns {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*patternEntry).key)
+	}
+	return re, nil
+}
+
+// compilePattern translates a pattern into a regular expression.
+func compilePattern(op, pattern, escape string) (*regexp.Regexp, error) {
+	var expr string
+	var err error
+	switch op {
+	case "LIKE", "ILIKE":
+		expr, err = likeToRegexp(pattern, escape, op == "ILIKE")
+	case "GLOB":
+		expr = globToRegexp(pattern)
+	case "REGEXP":
+		expr = pattern
+	default:
+		return nil, fmt.Errorf("unknown pattern operator %s", op)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s pattern %q: %w", op, pattern, err)
+	}
+	return re, nil
+}
+
+// likeToRegexp translates a LIKE pattern, in which % matches any run of
+// characters and _ any single character, into an anchored regular
+// expression.
+func likeToRegexp(pattern, escape string, fold bool) (string, error) {
+	if utf8.RuneCountInString(escape) > 1 {
+		return "", fmt.Errorf("ESCAPE must be a single character, not %q", escape)
+	}
+
+	var b strings.Builder
+	b.WriteString("(?s)")
+	if fold {
+		b.WriteString("(?i)")
+	}
+	b.WriteString("^")
+
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case escape != "" && string(r) == escape:
+			i++
+			if i == len(runes) {
+				return "", fmt.Errorf("LIKE pattern %q must not end with the escape character", pattern)
+			}
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	b.WriteString("$")
+	return b.String(), nil
+}
+
+// globToRegexp translates a case-sensitive GLOB pattern, in which * matches
+// any run of characters, ? any single character and [...] or [!...] a
+// character class, into an anchored regular expression.
+func globToRegexp(pattern string) string {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			// A class runs to the next ], which may itself be the first
+			// member; without one the [ is literal.
+			end := i + 1
+			if end < len(runes) && runes[end] == '!' {
+				end++
+			}
+			if end < len(runes) && runes[end] == ']' {
+				end++
+			}
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end == len(runes) {
+				b.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+
+			members := runes[i+1 : end]
+			b.WriteString("[")
+			if len(members) > 0 && members[0] == '!' {
+				b.WriteString("^")
+				members = members[1:]
+			}
+			for _, m := range members {
+				if m == '\\' || m == '[' || m == ']' || m == '^' {
+					b.WriteString(`\`)
+				}
+				b.WriteRune(m)
+			}
+			b.WriteString("]")
+			i = end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	b.WriteString("$")
+	return b.String()
+}
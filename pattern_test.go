@@ -0,0 +1,134 @@
+package sheetsql
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestEvalEnv_matchPattern(t *testing.T) {
+	env := &evalEnv{query: (&Client{}).From("Users")}
+
+	tests := []struct {
+		operator string
+		value    string
+		pattern  string
+		escape   string
+		expected bool
+		wantErr  string
+	}{
+		{"LIKE", "John", "J%", `\`, true, ""},
+		{"LIKE", "John", "_ohn", `\`, true, ""},
+		{"LIKE", "John", "_hn", `\`, false, ""},
+		{"LIKE", "John", "john", `\`, false, ""},
+		{"LIKE", "John", "Jo", `\`, false, ""},
+		{"LIKE", "a.b", "a_b", `\`, true, ""},
+		{"LIKE", "axb", "a.b", `\`, false, ""},
+		{"LIKE", "line\nbreak", "line%", `\`, true, ""},
+		{"LIKE", "50%", `50\%`, `\`, true, ""},
+		{"LIKE", "500", `50\%`, `\`, false, ""},
+		{"LIKE", "50%", "50!%", "!", true, ""},
+		{"LIKE", `a\b`, `a\b`, "", true, ""},
+		{"NOT LIKE", "John", "J%", `\`, false, ""},
+		{"ILIKE", "John", "jo%", `\`, true, ""},
+		{"NOT ILIKE", "John", "JO%", `\`, false, ""},
+		{"GLOB", "report.csv", "*.csv", "", true, ""},
+		{"GLOB", "Report.CSV", "*.csv", "", false, ""},
+		{"GLOB", "a1", "a?", "", true, ""},
+		{"GLOB", "b7", "[a-c][0-9]", "", true, ""},
+		{"GLOB", "b7", "[!a-c]*", "", false, ""},
+		{"GLOB", "[x", "[x", "", true, ""},
+		{"REGEXP", "John", "^J.h?n$", "", true, ""},
+		{"REGEXP", "John", "oh", "", true, ""},
+		{"~", "John", "(?i)^JOHN$", "", true, ""},
+		{"!~", "John", "^J", "", false, ""},
+		{"LIKE", "John", `J\`, `\`, false, `LIKE pattern "J\\" must not end with the escape character`},
+		{"LIKE", "John", "J%", "!!", false, `ESCAPE must be a single character, not "!!"`},
+		{"REGEXP", "John", "(", "", false, "invalid REGEXP pattern \"(\": error parsing regexp: missing closing ): `(`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.operator+" "+tt.pattern, func(t *testing.T) {
+			result, err := env.matchPattern(tt.operator, tt.value, tt.pattern, tt.escape)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("matchPattern() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("matchPattern() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("matchPattern(%q, %q) = %v, expected %v", tt.value, tt.pattern, result, tt.expected)
+			}
+		})
+	}
+
+	patterns := &env.query.patterns
+	if len(patterns.entries) == 0 {
+		t.Fatal("matchPattern() did not cache compiled patterns")
+	}
+	cached := patterns.entries["LIKE\x00\\\x00J%"]
+	if _, err := env.matchPattern("LIKE", "Jane", "J%", `\`); err != nil {
+		t.Fatalf("matchPattern() error = %v", err)
+	}
+	if cached == nil || patterns.entries["LIKE\x00\\\x00J%"] != cached {
+		t.Error("matchPattern() compiled a cached pattern again")
+	}
+
+	for i := 0; i < 2*maxCachedPatterns; i++ {
+		if _, err := env.matchPattern("GLOB", "John", fmt.Sprintf("J*%d", i), ""); err != nil {
+			t.Fatalf("matchPattern() error = %v", err)
+		}
+	}
+	if len(patterns.entries) != maxCachedPatterns || patterns.order.Len() != maxCachedPatterns {
+		t.Errorf("matchPattern() kept %d patterns, expected %d", len(patterns.entries), maxCachedPatterns)
+	}
+}
+
+func TestSQLParser_Query_Patterns(t *testing.T) {
+	parser := NewSQLParser(SetupTestData().Client("test-id"))
+
+	tests := []struct {
+		name     string
+		where    string
+		expected []int
+	}{
+		{"prefix", "Name LIKE 'J%'", []int{1, 2}},
+		{"single character", "Name LIKE '_ob %'", []int{3}},
+		{"case sensitive", "Name LIKE 'j%'", nil},
+		{"ilike", "Name ILIKE 'j%'", []int{1, 2}},
+		{"not like", "City NOT LIKE 'New %'", []int{2, 3, 5}},
+		{"escape", "Email LIKE '%!_%' ESCAPE '!'", nil},
+		{"glob", "Email GLOB '[a-c]*@example.com'", []int{3, 4, 5}},
+		{"regexp", "Name REGEXP '^[A-C]'", []int{3, 4, 5}},
+		{"tilde", "Name ~ 'son$' OR Name !~ 'o'", []int{2, 3, 5}},
+		{"contains", "Name CONTAINS 'JOHN'", []int{1, 3}},
+		{"not contains", "Name NOT CONTAINS 'o'", []int{2}},
+		{"numbers", "Age LIKE '2_'", []int{2, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var users []User
+			if err := parser.Query("SELECT * FROM Users WHERE "+tt.where, &users); err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+
+			var ids []int
+			for _, user := range users {
+				ids = append(ids, user.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("Query() IDs = %v, expected %v", ids, tt.expected)
+			}
+		})
+	}
+
+	var users []User
+	err := parser.Query("SELECT * FROM Users WHERE Name REGEXP '['", &users)
+	if err == nil || err.Error() != "invalid REGEXP pattern \"[\": error parsing regexp: missing closing ]: `[`" {
+		t.Errorf("Query() error = %v, expected invalid pattern", err)
+	}
+}
@@ -0,0 +1,189 @@
+package sheetsql
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// pivotClause reshapes the table a query reads before the query runs on it.
+// PIVOT turns the values of column into columns that hold agg of the
+// matching rows, grouped by the table's remaining columns. UNPIVOT turns the
+// columns listed in into rows holding the column's name in column and its
+// cell in value.
+type pivotClause struct {
+	unpivot bool
+	agg     *aggregateExpr // PIVOT only
+	value   string         // UNPIVOT only
+	column  string
+	in      []pivotColumn
+}
+
+// pivotColumn is one entry of the IN list. For PIVOT, name is the result
+// column that collects the rows where the FOR column is value; for UNPIVOT,
+// name is a column of the table.
+type pivotColumn struct {
+	value interface{}
+	name  string
+}
+
+func (pc *pivotClause) String() string {
+	in := make([]string, len(pc.in))
+	for i, c := range pc.in {
+		if pc.unpivot {
+			in[i] = quoteIdentifier(c.name)
+			continue
+		}
+		in[i] = formatLiteral(c.value)
+		if c.name != formatValue(c.value) {
+			in[i] += " AS " + quoteIdentifier(c.name)
+		}
+	}
+
+	if pc.unpivot {
+		return fmt.Sprintf("UNPIVOT (%s FOR %s IN (%s))", quoteIdentifier(pc.value), quoteIdentifier(pc.column), strings.Join(in, ", "))
+	}
+	return fmt.Sprintf("PIVOT (%s FOR %s IN (%s))", pc.agg, quoteIdentifier(pc.column), strings.Join(in, ", "))
+}
+
+// pivotTable replaces the columns and rows of t, the table the query is on,
+// with those of its PIVOT or UNPIVOT clause.
+func (q *Query) pivotTable(exec *execution, t *table) error {
+	fieldMap := make(map[string]int, len(t.headers))
+	for i, header := range t.headers {
+		if _, exists := fieldMap[header]; !exists {
+			fieldMap[header] = i
+		}
+	}
+
+	if q.pivot.unpivot {
+		return q.pivot.unpivotTable(t, fieldMap)
+	}
+	return q.pivot.pivotTable(&evalEnv{query: q, fieldMap: fieldMap, exec: exec}, t)
+}
+
+func (pc *pivotClause) pivotTable(base *evalEnv, t *table) error {
+	forIndex, ok := base.fieldMap[pc.column]
+	if !ok {
+		return fmt.Errorf("unknown column %q in PIVOT", pc.column)
+	}
+	if err := checkColumns(pc.agg.arg, base.fieldMap, "PIVOT"); err != nil {
+		return err
+	}
+
+	// The FOR column and the columns the aggregate reads are replaced by
+	// the pivoted ones; the rest identify a result row.
+	consumed := map[int]bool{forIndex: true}
+	walkExpr(pc.agg.arg, func(e expr) bool {
+		if ref, ok := e.(*columnRef); ok {
+			consumed[base.fieldMap[ref.key()]] = true
+		}
+		return true
+	})
+
+	var keep []int
+	var headers []string
+	for i, header := range t.headers {
+		if !consumed[i] {
+			keep = append(keep, i)
+			headers = append(headers, header)
+		}
+	}
+
+	buckets := make(map[string]int, len(pc.in))
+	for i, c := range pc.in {
+		if slices.Contains(headers, c.name) {
+			return fmt.Errorf("PIVOT column %q already exists", c.name)
+		}
+		headers = append(headers, c.name)
+		buckets[formatValue(c.value)] = i
+	}
+
+	// Every row starts or joins a group, even when its FOR column matches
+	// none of the values.
+	var groups [][]interface{}
+	var groupRows [][][]*evalEnv
+	index := make(map[string]int)
+	for _, row := range t.rows {
+		row = padRow(row, len(t.headers))
+		kept := make([]interface{}, len(keep))
+		for i, j := range keep {
+			kept[i] = row[j]
+		}
+
+		key := rowKey(kept)
+		g, seen := index[key]
+		if !seen {
+			g = len(groups)
+			index[key] = g
+			groups = append(groups, kept)
+			groupRows = append(groupRows, make([][]*evalEnv, len(pc.in)))
+		}
+		if isBlank(row[forIndex]) {
+			continue
+		}
+		if b, ok := buckets[formatValue(row[forIndex])]; ok {
+			groupRows[g][b] = append(groupRows[g][b], base.with(row))
+		}
+	}
+
+	rows := make([][]interface{}, len(groups))
+	for g, kept := range groups {
+		rows[g] = kept
+		for _, envs := range groupRows[g] {
+			value, err := pc.agg.compute(envs)
+			if err != nil {
+				return err
+			}
+			rows[g] = append(rows[g], value)
+		}
+	}
+
+	t.headers, t.rows = headers, rows
+	return nil
+}
+
+func (pc *pivotClause) unpivotTable(t *table, fieldMap map[string]int) error {
+	columns := make([]int, len(pc.in))
+	for i, c := range pc.in {
+		j, ok := fieldMap[c.name]
+		if !ok {
+			return fmt.Errorf("unknown column %q in UNPIVOT", c.name)
+		}
+		columns[i] = j
+	}
+
+	var keep []int
+	var headers []string
+	for i, header := range t.headers {
+		if !slices.Contains(columns, i) {
+			keep = append(keep, i)
+			headers = append(headers, header)
+		}
+	}
+	for _, name := range []string{pc.column, pc.value} {
+		if slices.Contains(headers, name) {
+			return fmt.Errorf("UNPIVOT column %q already exists", name)
+		}
+		headers = append(headers, name)
+	}
+
+	// Empty cells make no row, as a missing value would in a long layout.
+	var rows [][]interface{}
+	for _, row := range t.rows {
+		row = padRow(row, len(t.headers))
+		for i, j := range columns {
+			if isBlank(row[j]) {
+				continue
+			}
+			long := make([]interface{}, 0, len(headers))
+			for _, k := range keep {
+				long = append(long, row[k])
+			}
+			rows = append(rows, append(long, pc.in[i].name, row[j]))
+		}
+	}
+
+	t.headers, t.rows = headers, rows
+	return nil
+}
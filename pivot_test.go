@@ -0,0 +1,145 @@
+package sheetsql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newPivotTestBackend() *countingBackend {
+	backend := &countingBackend{MemoryBackend: NewMemoryBackend()}
+	backend.SetSheet("Budget", [][]interface{}{
+		{"Region", "Jan", "Feb", "Mar"},
+		{"North", "10", "20"},
+		{"South", "5", "", "7"},
+	})
+	backend.SetSheet("Sales", [][]interface{}{
+		{"Region", "Month", "Amount"},
+		{"North", "Jan", "10"},
+		{"North", "Feb", "20"},
+		{"South", "Jan", "5"},
+		{"North", "Jan", "1"},
+		{"South", "Mar", "7"},
+		{"West", "Apr", "3"},
+	})
+	return backend
+}
+
+func TestQuery_Pivot(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		columns  []string
+		expected [][]interface{}
+		wantErr  string
+	}{
+		{
+			name:     "unpivot",
+			sql:      "SELECT * FROM Budget UNPIVOT (Amount FOR Month IN (Jan, Feb, Mar))",
+			columns:  []string{"Region", "Month", "Amount"},
+			expected: [][]interface{}{{"North", "Jan", "10"}, {"North", "Feb", "20"}, {"South", "Jan", "5"}, {"South", "Mar", "7"}},
+		},
+		{
+			name:     "unpivot and aggregate",
+			sql:      "SELECT Month, SUM(Amount) AS Total FROM Budget UNPIVOT (Amount FOR Month IN (Jan, Feb, Mar)) GROUP BY Month ORDER BY Total DESC",
+			columns:  []string{"Month", "Total"},
+			expected: [][]interface{}{{"Feb", int64(20)}, {"Jan", int64(15)}, {"Mar", int64(7)}},
+		},
+		{
+			name:     "pivot",
+			sql:      "SELECT * FROM Sales PIVOT (SUM(Amount) FOR Month IN ('Jan', 'Feb', 'Mar' AS March))",
+			columns:  []string{"Region", "Jan", "Feb", "March"},
+			expected: [][]interface{}{{"North", int64(11), int64(20), nil}, {"South", int64(5), nil, int64(7)}, {"West", nil, nil, nil}},
+		},
+		{
+			name:     "pivot with alias",
+			sql:      "SELECT Region, p.Jan FROM Sales PIVOT (COUNT(Amount) FOR Month IN ('Jan')) p WHERE p.Jan > 0",
+			columns:  []string{"Region", "Jan"},
+			expected: [][]interface{}{{"North", 2}, {"South", 1}},
+		},
+		{
+			name: "pivot then unpivot",
+			sql: "WITH wide AS (SELECT * FROM Sales PIVOT (MAX(Amount) FOR Month IN ('Jan', 'Mar'))) " +
+				"SELECT * FROM wide UNPIVOT (Amount FOR Month IN (Jan, Mar))",
+			columns:  []string{"Region", "Month", "Amount"},
+			expected: [][]interface{}{{"North", "Jan", "10"}, {"South", "Jan", "5"}, {"South", "Mar", "7"}},
+		},
+		{
+			name:    "unknown FOR column",
+			sql:     "SELECT * FROM Sales PIVOT (SUM(Amount) FOR Period IN ('Jan'))",
+			wantErr: `unknown column "Period" in PIVOT`,
+		},
+		{
+			name:    "unknown aggregate column",
+			sql:     "SELECT * FROM Sales PIVOT (SUM(Total) FOR Month IN ('Jan'))",
+			wantErr: `unknown column "Total" in PIVOT`,
+		},
+		{
+			name:    "pivot column taken",
+			sql:     "SELECT * FROM Sales PIVOT (SUM(Amount) FOR Month IN ('Region'))",
+			wantErr: `PIVOT column "Region" already exists`,
+		},
+		{
+			name:    "unknown UNPIVOT column",
+			sql:     "SELECT * FROM Budget UNPIVOT (Amount FOR Month IN (Jan, Apr))",
+			wantErr: `unknown column "Apr" in UNPIVOT`,
+		},
+		{
+			name:    "unpivot column taken",
+			sql:     "SELECT * FROM Budget UNPIVOT (Amount FOR Region IN (Jan))",
+			wantErr: `UNPIVOT column "Region" already exists`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newPivotTestBackend()
+			query, err := NewSQLParser(NewClientWithBackend("test-id", backend)).parseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("parseSQL() error = %v", err)
+			}
+			if got := query.String(); got != tt.sql {
+				t.Errorf("String() = %q, expected %q", got, tt.sql)
+			}
+
+			columns, rows, err := query.rows()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("rows() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rows() error = %v", err)
+			}
+			if !reflect.DeepEqual(columns, tt.columns) {
+				t.Errorf("columns = %v, expected %v", columns, tt.columns)
+			}
+			if !reflect.DeepEqual(rows, tt.expected) {
+				t.Errorf("rows = %v, expected %v", rows, tt.expected)
+			}
+			if backend.reads != 1 {
+				t.Errorf("query made %d read requests, expected 1", backend.reads)
+			}
+		})
+	}
+}
+
+func TestSQLParser_Query_Unpivot(t *testing.T) {
+	parser := NewSQLParser(NewClientWithBackend("test-id", newPivotTestBackend()))
+
+	type entry struct {
+		Region string `sheet:"Region"`
+		Month  string `sheet:"Month"`
+		Amount int    `sheet:"Amount"`
+	}
+	var entries []entry
+	err := parser.Query("SELECT * FROM Budget UNPIVOT (Amount FOR Month IN (Jan, Feb, Mar)) WHERE Amount > ? ORDER BY Amount", &entries, 5)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	expected := []entry{{"South", "Mar", 7}, {"North", "Jan", 10}, {"North", "Feb", 20}}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Query() = %v, expected %v", entries, expected)
+	}
+}
@@ -0,0 +1,172 @@
+package sheetsql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// setOp is a UNION, INTERSECT or EXCEPT with the query on its right. Set
+// operations are applied from left to right; an INTERSECT that binds to the
+// right-hand query of a UNION or EXCEPT is one of that query's own set
+// operations. Without all, the result has no duplicate rows.
+type setOp struct {
+	op    string
+	all   bool
+	query *Query
+}
+
+func (o setOp) String() string {
+	if o.all {
+		return o.op + " ALL " + o.query.String()
+	}
+	return o.op + " " + o.query.String()
+}
+
+// combine applies q's set operations to rows, the result of q's own SELECT,
+// then sorts and pages the combined rows.
+func (q *Query) combine(exec *execution, outer *evalEnv, columns []string, rows [][]interface{}) ([]string, [][]interface{}, error) {
+	for _, op := range q.setOps {
+		opColumns, opRows, err := op.query.run(exec, outer)
+		if err != nil {
+			return nil, nil, err
+		}
+		// The combined rows depend on the outer row if any of them do.
+		if exec.correlated[op.query] {
+			exec.correlated[q] = true
+		}
+
+		switch {
+		case columns == nil:
+			columns = opColumns
+		case opColumns != nil && len(opColumns) != len(columns):
+			return nil, nil, fmt.Errorf("queries combined with %s have %d and %d columns", op.op, len(columns), len(opColumns))
+		}
+		rows = op.apply(rows, opRows)
+	}
+	if columns == nil {
+		return nil, nil, nil
+	}
+
+	fieldMap := make(map[string]int)
+	for i, column := range columns {
+		fieldMap[column] = i
+	}
+	base := &evalEnv{query: q, fieldMap: fieldMap, exec: exec, outer: outer}
+
+	envs := make([]*evalEnv, len(rows))
+	for i, row := range rows {
+		envs[i] = base.with(row)
+	}
+	if err := q.sortRows(envs, fieldMap); err != nil {
+		return nil, nil, err
+	}
+
+	envs = paginate(envs, q.offset, q.limit)
+	rows = make([][]interface{}, len(envs))
+	for i, env := range envs {
+		rows[i] = env.row
+	}
+
+	return columns, rows, nil
+}
+
+// apply returns the rows of left combined with right.
+func (o setOp) apply(left, right [][]interface{}) [][]interface{} {
+	var rows [][]interface{}
+	if o.op == "UNION" {
+		rows = append(left, right...)
+	} else {
+		counts := make(map[string]int)
+		for _, row := range right {
+			counts[rowKey(row)]++
+		}
+		for _, row := range left {
+			key := rowKey(row)
+			if (counts[key] > 0) == (o.op == "INTERSECT") {
+				rows = append(rows, row)
+			}
+			// With ALL, each row of right is matched by one row of left.
+			if o.all {
+				counts[key]--
+			}
+		}
+	}
+
+	if !o.all {
+		rows = distinctRows(rows)
+	}
+	return rows
+}
+
+// checkDistinctOrder checks that each ORDER BY item of a SELECT DISTINCT is
+// fixed by the selected values: it is one of the selected expressions, or
+// reads only selected columns. Otherwise the order would depend on which of
+// the duplicate rows was kept.
+func (q *Query) checkDistinctOrder(fieldMap map[string]int) error {
+	selected := make(map[int]bool)
+	exprs := make(map[string]bool)
+	for _, item := range q.columns {
+		if item.star {
+			return nil
+		}
+		exprs[item.expr.String()] = true
+		if ref, ok := item.expr.(*columnRef); ok {
+			selected[fieldMap[ref.key()]] = true
+		}
+	}
+	if len(q.columns) == 0 {
+		return nil
+	}
+
+	for _, item := range q.orderBy {
+		e := q.resolveAliases(item.expr, fieldMap)
+		if exprs[e.String()] {
+			continue
+		}
+		fixed := true
+		walkExpr(e, func(e expr) bool {
+			switch e := e.(type) {
+			case *aggregateExpr, *windowExpr:
+				fixed = false
+			case *columnRef:
+				// Unknown columns are reported by sortRows.
+				colIndex, exists := fieldMap[e.key()]
+				if exists && !selected[colIndex] && colIndex != outerColumn {
+					fixed = false
+				}
+			}
+			return fixed
+		})
+		if !fixed {
+			return fmt.Errorf("ORDER BY %s must be in the select list of SELECT DISTINCT", item.expr)
+		}
+	}
+	return nil
+}
+
+// distinctRows returns the first of every set of equal rows.
+func distinctRows(rows [][]interface{}) [][]interface{} {
+	seen := make(map[string]bool)
+	var distinct [][]interface{}
+	for _, row := range rows {
+		key := rowKey(row)
+		if !seen[key] {
+			seen[key] = true
+			distinct = append(distinct, row)
+		}
+	}
+	return distinct
+}
+
+// rowKey returns a string that is equal for rows whose values are. Values are
+// compared as text, and NULL and empty cells are alike, as when sorting.
+func rowKey(row []interface{}) string {
+	var sb strings.Builder
+	for _, value := range row {
+		if !isBlank(value) {
+			sb.WriteString(formatValue(value))
+		}
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
@@ -0,0 +1,178 @@
+package sheetsql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newMonthsBackend() *countingBackend {
+	backend := &countingBackend{MemoryBackend: NewMemoryBackend()}
+	backend.SetSheet("Jan", [][]interface{}{
+		{"Customer", "Product", "Qty"},
+		{"Ann", "pen", "2"},
+		{"Ben", "ink", "1"},
+		{"Ann", "pen", "2"},
+	})
+	backend.SetSheet("Feb", [][]interface{}{
+		{"Customer", "Product", "Qty"},
+		{"Ann", "pen", "2"},
+		{"Cat", "pad", "5"},
+		{"Ben", "ink"},
+	})
+	backend.SetSheet("Mar", [][]interface{}{
+		{"Customer", "Product", "Qty"},
+		{"Cat", "pad", "3"},
+	})
+	backend.SetSheet("Empty", nil)
+	return backend
+}
+
+func TestQuery_SetOperations(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected [][]interface{}
+		wantErr  string
+	}{
+		{
+			name:     "distinct",
+			sql:      "SELECT DISTINCT Customer, Product FROM Jan",
+			expected: [][]interface{}{{"Ann", "pen"}, {"Ben", "ink"}},
+		},
+		{
+			name:     "distinct keeps sort order before limit",
+			sql:      "SELECT DISTINCT Customer FROM Jan ORDER BY Customer DESC LIMIT 1 OFFSET 1",
+			expected: [][]interface{}{{"Ann"}},
+		},
+		{
+			name:     "distinct order by an expression of selected columns",
+			sql:      "SELECT DISTINCT Customer AS Name, Qty FROM Jan ORDER BY Qty * 1 DESC, Name",
+			expected: [][]interface{}{{"Ann", "2"}, {"Ben", "1"}},
+		},
+		{
+			name:    "distinct order by a column not selected",
+			sql:     "SELECT DISTINCT Customer FROM Jan ORDER BY Qty",
+			wantErr: "ORDER BY Qty must be in the select list of SELECT DISTINCT",
+		},
+		{
+			name:    "distinct order by an aggregate not selected",
+			sql:     "SELECT DISTINCT Customer FROM Jan GROUP BY Customer ORDER BY COUNT(*)",
+			wantErr: "ORDER BY COUNT(*) must be in the select list of SELECT DISTINCT",
+		},
+		{
+			name:     "union removes duplicates",
+			sql:      "SELECT Customer FROM Jan UNION SELECT Customer FROM Feb UNION SELECT Customer FROM Mar",
+			expected: [][]interface{}{{"Ann"}, {"Ben"}, {"Cat"}},
+		},
+		{
+			name:     "union all keeps them",
+			sql:      "SELECT Customer, Qty FROM Jan WHERE Customer = 'Ann' UNION ALL SELECT Customer, Qty FROM Feb WHERE Customer = 'Ann'",
+			expected: [][]interface{}{{"Ann", "2"}, {"Ann", "2"}, {"Ann", "2"}},
+		},
+		{
+			name:     "order by and limit apply to the whole",
+			sql:      "SELECT Customer, Qty FROM Feb UNION ALL SELECT Customer, Qty FROM Mar ORDER BY Qty DESC NULLS LAST LIMIT 2",
+			expected: [][]interface{}{{"Cat", "5"}, {"Cat", "3"}},
+		},
+		{
+			name:     "intersect",
+			sql:      "SELECT * FROM Jan INTERSECT SELECT * FROM Feb",
+			expected: [][]interface{}{{"Ann", "pen", "2"}},
+		},
+		{
+			name:     "intersect all",
+			sql:      "SELECT Customer FROM Jan INTERSECT ALL SELECT Customer FROM Feb",
+			expected: [][]interface{}{{"Ann"}, {"Ben"}},
+		},
+		{
+			name:     "except",
+			sql:      "SELECT Customer FROM Jan EXCEPT SELECT Customer FROM Mar",
+			expected: [][]interface{}{{"Ann"}, {"Ben"}},
+		},
+		{
+			name:     "except removes every copy",
+			sql:      "SELECT Customer FROM Jan EXCEPT SELECT Customer FROM Feb WHERE Qty = 2",
+			expected: [][]interface{}{{"Ben"}},
+		},
+		{
+			name:     "except all removes one copy per row",
+			sql:      "SELECT Customer FROM Jan EXCEPT ALL SELECT Customer FROM Feb WHERE Qty = 2",
+			expected: [][]interface{}{{"Ben"}, {"Ann"}},
+		},
+		{
+			name:     "intersect binds before union",
+			sql:      "SELECT Customer FROM Mar UNION SELECT Customer FROM Jan INTERSECT SELECT Customer FROM Empty",
+			expected: [][]interface{}{{"Cat"}},
+		},
+		{
+			name:     "intersect binds before except",
+			sql:      "SELECT Customer FROM Feb EXCEPT SELECT Customer FROM Jan INTERSECT SELECT Customer FROM Mar UNION SELECT Customer FROM Mar",
+			expected: [][]interface{}{{"Ann"}, {"Cat"}, {"Ben"}},
+		},
+		{
+			name:     "null and empty cells are alike",
+			sql:      "SELECT Customer, Qty FROM Feb WHERE Customer = 'Ben' UNION SELECT Customer, '' FROM Jan WHERE Customer = 'Ben'",
+			expected: [][]interface{}{{"Ben", nil}},
+		},
+		{
+			name:     "columns are named by the first query",
+			sql:      "SELECT Customer AS Name FROM Mar UNION SELECT Product FROM Mar ORDER BY Name",
+			expected: [][]interface{}{{"Cat"}, {"pad"}},
+		},
+		{
+			name:     "empty sheet",
+			sql:      "SELECT Customer FROM Empty UNION SELECT Customer FROM Mar",
+			expected: [][]interface{}{{"Cat"}},
+		},
+		{
+			name:     "in subquery",
+			sql:      "SELECT Customer FROM Feb WHERE Customer NOT IN (SELECT Customer FROM Jan UNION SELECT Customer FROM Empty)",
+			expected: [][]interface{}{{"Cat"}},
+		},
+		{
+			name:     "correlated operand",
+			sql:      "SELECT Customer FROM Feb WHERE EXISTS (SELECT Product FROM Jan WHERE 1 = 0 UNION SELECT Product FROM Mar WHERE Mar.Customer = Feb.Customer)",
+			expected: [][]interface{}{{"Cat"}},
+		},
+		{
+			name:    "column count",
+			sql:     "SELECT Customer FROM Jan UNION SELECT Customer, Qty FROM Feb",
+			wantErr: "queries combined with UNION have 1 and 2 columns",
+		},
+		{
+			name:    "order by a column not selected",
+			sql:     "SELECT Customer FROM Jan UNION SELECT Customer FROM Feb ORDER BY Qty",
+			wantErr: `unknown column "Qty" in ORDER BY`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newMonthsBackend()
+			query, err := NewSQLParser(NewClientWithBackend("test-id", backend)).parseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("parseSQL() error = %v", err)
+			}
+			if got := query.String(); got != tt.sql {
+				t.Errorf("String() = %q, expected %q", got, tt.sql)
+			}
+
+			_, rows, err := query.rows()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("rows() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rows() error = %v", err)
+			}
+			if !reflect.DeepEqual(rows, tt.expected) {
+				t.Errorf("rows = %v, expected %v", rows, tt.expected)
+			}
+			if backend.reads != 1 {
+				t.Errorf("query made %d read requests, expected 1", backend.reads)
+			}
+		})
+	}
+}
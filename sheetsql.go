@@ -4,24 +4,117 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
 type Client struct {
-	service       *sheets.Service
+	backend       Backend
 	spreadsheetID string
+
+	// dateLayouts are the layouts dates in cells are read with after ISO
+	// 8601, or nil for defaultDateLayouts.
+	dateLayouts []string
+
+	// operators holds the operators added with RegisterOperator, by
+	// upper-case name.
+	operators map[string]Operator
+
+	// operatorsVersion counts RegisterOperator calls. Parsers cache it with
+	// each statement, since a new operator changes what SQL parses to.
+	operatorsVersion int
+
+	// clock is what NOW() reads, or nil for time.Now.
+	clock func() time.Time
 }
 
 type Query struct {
 	client    *Client
 	sheetName string
-	where     []WhereClause
+	cte       *cte         // read instead of the sheet, if set
+	pivot     *pivotClause // reshapes the sheet before the query runs, if set
+	alias     string
+	joins     []joinClause
+	columns   []selectItem
+	distinct  bool
+	where     expr
+	groupBy   []expr
+	having    expr
+	orderBy   []orderItem
 	limit     int
 	offset    int
+
+	// setOps combine the rows of further queries with q's own; orderBy,
+	// limit and offset then apply to the combined rows.
+	setOps []setOp
+
+	// with holds the common table expressions of a WITH clause, which the
+	// query and its subqueries have already been resolved against.
+	with []*cte
+
+	// err records a mistake made while building the query, such as an
+	// invalid sort direction, and is returned when the query runs.
+	err error
+
+	// patterns caches the compiled LIKE, GLOB and REGEXP patterns.
+	patterns patternCache
+}
+
+// selectItem is one entry of a select list: either * or an expression with
+// an optional alias.
+type selectItem struct {
+	star  bool
+	expr  expr
+	alias string
+}
+
+// name returns the column name the item has in the result.
+func (s selectItem) name() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	if ref, ok := s.expr.(*columnRef); ok {
+		return ref.name
+	}
+	return s.expr.String()
+}
+
+// orderItem is one sort key of an ORDER BY clause. NULLs sort after every
+// other value unless nullsFirst is set.
+type orderItem struct {
+	expr       expr
+	desc       bool
+	nullsFirst bool
+}
+
+func (s selectItem) String() string {
+	if s.star {
+		return "*"
+	}
+	if s.alias != "" {
+		return s.expr.String() + " AS " + quoteIdentifier(s.alias)
+	}
+	return s.expr.String()
+}
+
+func (o orderItem) String() string {
+	str := o.expr.String()
+	if o.desc {
+		str += " DESC"
+	}
+	if o.nullsFirst != o.desc {
+		if o.nullsFirst {
+			str += " NULLS FIRST"
+		} else {
+			str += " NULLS LAST"
+		}
+	}
+	return str
 }
 
 type WhereClause struct {
@@ -36,26 +129,267 @@ func NewClient(ctx context.Context, spreadsheetID string, opts ...option.ClientO
 		return nil, fmt.Errorf("failed to create sheets service: %w", err)
 	}
 
+	return NewClientWithBackend(spreadsheetID, NewSheetsBackend(srv)), nil
+}
+
+func NewClientWithBackend(spreadsheetID string, backend Backend) *Client {
 	return &Client{
-		service:       srv,
+		backend:       backend,
 		spreadsheetID: spreadsheetID,
-	}, nil
+	}
 }
 
 func (c *Client) From(sheetName string) *Query {
 	return &Query{
 		client:    c,
 		sheetName: sheetName,
-		where:     make([]WhereClause, 0),
 	}
 }
 
+// Where adds a condition that rows must also satisfy (AND). Besides the
+// comparison operators, operator may be [NOT] CONTAINS, a pattern operator
+// ([NOT] LIKE, ILIKE, GLOB or REGEXP, ~ or !~), IN or NOT IN with a slice or
+// a one-column *Query as value, BETWEEN or NOT BETWEEN with a two-element
+// slice, IS NULL or IS NOT NULL with a nil value, or an operator registered
+// with RegisterOperator, optionally preceded by NOT.
 func (q *Query) Where(column, operator string, value interface{}) *Query {
-	q.where = append(q.where, WhereClause{
-		Column:   column,
-		Operator: operator,
-		Value:    value,
-	})
+	q.where = and(q.where, q.condition(column, operator, value))
+	return q
+}
+
+// OrWhere adds a condition that rows may satisfy instead of the conditions
+// added so far, so Where(a).Where(b).OrWhere(c) means (a AND b) OR c.
+func (q *Query) OrWhere(column, operator string, value interface{}) *Query {
+	q.where = or(q.where, q.condition(column, operator, value))
+	return q
+}
+
+// operatorFunc applies a built-in operator to two values formatted as text.
+type operatorFunc func(env *evalEnv, left, right string) (bool, error)
+
+// builtinOperators are the binary operators applyOperator understands besides
+// those registered on the client.
+var builtinOperators map[string]operatorFunc
+
+func init() {
+	builtinOperators = map[string]operatorFunc{
+		"=":            func(env *evalEnv, a, b string) (bool, error) { return a == b, nil },
+		"!=":           func(env *evalEnv, a, b string) (bool, error) { return a != b, nil },
+		">":            compareOperator(">"),
+		"<":            compareOperator("<"),
+		">=":           compareOperator(">="),
+		"<=":           compareOperator("<="),
+		"CONTAINS":     containsOperator(false),
+		"NOT CONTAINS": containsOperator(true),
+		"~":            patternOperator("~"),
+		"!~":           patternOperator("!~"),
+	}
+	builtinOperators["=="] = builtinOperators["="]
+	builtinOperators["<>"] = builtinOperators["!="]
+	for op := range patternOperators {
+		builtinOperators[op] = patternOperator(op)
+		builtinOperators["NOT "+op] = patternOperator("NOT " + op)
+	}
+}
+
+func compareOperator(op string) operatorFunc {
+	return func(env *evalEnv, a, b string) (bool, error) {
+		return env.query.compareValues(a, b, op), nil
+	}
+}
+
+// containsOperator matches values that contain the other, ignoring case.
+func containsOperator(not bool) operatorFunc {
+	return func(env *evalEnv, a, b string) (bool, error) {
+		return strings.Contains(strings.ToLower(a), strings.ToLower(b)) != not, nil
+	}
+}
+
+// patternOperator matches values against a pattern with the default LIKE
+// escape character.
+func patternOperator(op string) operatorFunc {
+	return func(env *evalEnv, value, pattern string) (bool, error) {
+		return env.matchPattern(op, value, pattern, defaultEscape)
+	}
+}
+
+// isOperator reports whether applyOperator understands op.
+func (q *Query) isOperator(op string) bool {
+	_, ok := builtinOperators[op]
+	return ok || q.client.customOperator(op) != nil
+}
+
+// condition builds the expression for a Where call. An unknown operator or
+// unsuitable value is recorded in q.err and yields nil.
+func (q *Query) condition(column, operator string, value interface{}) expr {
+	op := strings.ToUpper(strings.Join(strings.Fields(operator), " "))
+	ref := &columnRef{name: column}
+
+	if q.isOperator(op) {
+		return &WhereClause{Column: column, Operator: op, Value: value}
+	}
+
+	switch op {
+	case "IS NULL", "IS NOT NULL":
+		return &isNullExpr{operand: ref, not: op == "IS NOT NULL"}
+	case "IN", "NOT IN":
+		in := &inExpr{operand: ref, not: op == "NOT IN"}
+		if sub, ok := value.(*Query); ok {
+			in.query = sub
+			return in
+		}
+		values, ok := literalList(value)
+		if !ok {
+			q.setErr(fmt.Errorf("%s requires a slice or *Query value, got %T", op, value))
+			return nil
+		}
+		in.values = values
+		return in
+	case "BETWEEN", "NOT BETWEEN":
+		bounds, ok := literalList(value)
+		if !ok || len(bounds) != 2 {
+			q.setErr(fmt.Errorf("%s requires a slice of two values, got %v", op, value))
+			return nil
+		}
+		return &betweenExpr{operand: ref, low: bounds[0], high: bounds[1], not: op == "NOT BETWEEN"}
+	}
+
+	q.setErr(fmt.Errorf("unknown operator %q", operator))
+	return nil
+}
+
+// literalList turns a slice or array into literals.
+func literalList(value interface{}) ([]expr, bool) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, false
+	}
+
+	values := make([]expr, v.Len())
+	for i := range values {
+		values[i] = &literal{value: v.Index(i).Interface()}
+	}
+	return values, true
+}
+
+// setErr records the first error made while building the query.
+func (q *Query) setErr(err error) {
+	if q.err == nil {
+		q.err = err
+	}
+}
+
+// WhereGroup adds the conditions built by group as a single parenthesised
+// condition joined with AND:
+//
+//	q.Where("Age", ">", 18).WhereGroup(func(g *Query) {
+//		g.Where("City", "=", "Boston").OrWhere("City", "=", "Chicago")
+//	})
+func (q *Query) WhereGroup(group func(q *Query)) *Query {
+	q.where = and(q.where, q.group(group))
+	return q
+}
+
+// OrWhereGroup is WhereGroup joined with OR.
+func (q *Query) OrWhereGroup(group func(q *Query)) *Query {
+	q.where = or(q.where, q.group(group))
+	return q
+}
+
+// WhereNot adds the negation of the conditions built by group, joined with
+// AND.
+func (q *Query) WhereNot(group func(q *Query)) *Query {
+	if cond := q.group(group); cond != nil {
+		q.where = and(q.where, &notExpr{operand: cond})
+	}
+	return q
+}
+
+func (q *Query) group(group func(q *Query)) expr {
+	sub := q.client.From(q.sheetName)
+	group(sub)
+	if sub.err != nil {
+		q.setErr(sub.err)
+	}
+	return sub.where
+}
+
+// Select restricts the result to the given columns, in that order. Without
+// Select every column of the sheet is returned.
+func (q *Query) Select(columns ...string) *Query {
+	q.columns = make([]selectItem, len(columns))
+	for i, column := range columns {
+		q.columns[i] = selectItem{expr: &columnRef{name: column}}
+	}
+	return q
+}
+
+// GroupBy folds rows with the same values in the given columns into one
+// result row and adds the columns to the select list. Use Aggregate to add
+// columns computed over each group.
+func (q *Query) GroupBy(columns ...string) *Query {
+	for _, column := range columns {
+		ref := &columnRef{name: column}
+		q.groupBy = append(q.groupBy, ref)
+		q.columns = append(q.columns, selectItem{expr: ref})
+	}
+	return q
+}
+
+// Aggregate adds a column computed over each group, or over all rows without
+// GroupBy. fn is COUNT, SUM, AVG, MIN or MAX, optionally followed by
+// DISTINCT; column may be "*" for COUNT. The result is named alias.
+//
+//	client.From("Orders").GroupBy("City").Aggregate("SUM", "Total", "Revenue")
+func (q *Query) Aggregate(fn, column, alias string) *Query {
+	agg, err := parseAggregate(fn, column)
+	if err != nil {
+		q.setErr(err)
+		return q
+	}
+
+	q.columns = append(q.columns, selectItem{expr: agg, alias: alias})
+	return q
+}
+
+// Having adds a condition that groups must satisfy. column may name an
+// Aggregate alias.
+func (q *Query) Having(column, operator string, value interface{}) *Query {
+	op := strings.ToUpper(strings.Join(strings.Fields(operator), " "))
+	if !q.isOperator(op) {
+		q.setErr(fmt.Errorf("unknown operator %q", operator))
+		return q
+	}
+
+	q.having = and(q.having, &comparison{op: op, left: &columnRef{name: column}, right: &literal{value: value}})
+	return q
+}
+
+// OrderBy sorts the result by column. direction is "ASC" (or empty) or
+// "DESC", optionally followed by "NULLS FIRST" or "NULLS LAST"; empty cells
+// count as NULL and by default sort as if larger than any other value. Each
+// call adds a further sort key.
+func (q *Query) OrderBy(column, direction string) *Query {
+	item := orderItem{expr: &columnRef{name: column}}
+
+	words := strings.Fields(strings.ToUpper(direction))
+	if len(words) > 0 && (words[0] == "ASC" || words[0] == "DESC") {
+		item.desc = words[0] == "DESC"
+		words = words[1:]
+	}
+	item.nullsFirst = item.desc
+
+	switch {
+	case len(words) == 0:
+	case len(words) == 2 && words[0] == "NULLS" && words[1] == "FIRST":
+		item.nullsFirst = true
+	case len(words) == 2 && words[0] == "NULLS" && words[1] == "LAST":
+		item.nullsFirst = false
+	default:
+		q.setErr(fmt.Errorf("invalid sort direction %q", direction))
+	}
+
+	q.orderBy = append(q.orderBy, item)
 	return q
 }
 
@@ -69,7 +403,80 @@ func (q *Query) Offset(offset int) *Query {
 	return q
 }
 
+// String returns the query as a SELECT statement.
+func (q *Query) String() string {
+	var sb strings.Builder
+
+	if len(q.with) > 0 {
+		sb.WriteString(withString(q.with) + " ")
+	}
+	sb.WriteString("SELECT ")
+	if q.distinct {
+		sb.WriteString("DISTINCT ")
+	}
+	if len(q.columns) == 0 {
+		sb.WriteString("*")
+	}
+	for i, item := range q.columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(item.String())
+	}
+
+	sb.WriteString(" FROM " + quoteIdentifier(q.sheetName))
+	if q.pivot != nil {
+		sb.WriteString(" " + q.pivot.String())
+	}
+	if q.alias != "" {
+		sb.WriteString(" " + quoteIdentifier(q.alias))
+	}
+	for _, j := range q.joins {
+		sb.WriteString(" " + j.String())
+	}
+
+	if q.where != nil {
+		sb.WriteString(" WHERE " + q.where.String())
+	}
+	for i, e := range q.groupBy {
+		if i == 0 {
+			sb.WriteString(" GROUP BY ")
+		} else {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(e.String())
+	}
+	if q.having != nil {
+		sb.WriteString(" HAVING " + q.having.String())
+	}
+	for _, op := range q.setOps {
+		sb.WriteString(" " + op.String())
+	}
+	for i, item := range q.orderBy {
+		if i == 0 {
+			sb.WriteString(" ORDER BY ")
+		} else {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(item.String())
+	}
+
+	if q.limit > 0 {
+		fmt.Fprintf(&sb, " LIMIT %d", q.limit)
+	}
+	if q.offset > 0 {
+		fmt.Fprintf(&sb, " OFFSET %d", q.offset)
+	}
+
+	return sb.String()
+}
+
 func (q *Query) Get(dest interface{}) error {
+	return q.get(newExecution(q.client), dest)
+}
+
+// get runs the query within exec and appends the rows to dest.
+func (q *Query) get(exec *execution, dest interface{}) error {
 	destValue := reflect.ValueOf(dest)
 	if destValue.Kind() != reflect.Ptr || destValue.Elem().Kind() != reflect.Slice {
 		return fmt.Errorf("dest must be a pointer to a slice")
@@ -78,96 +485,391 @@ func (q *Query) Get(dest interface{}) error {
 	sliceValue := destValue.Elem()
 	elemType := sliceValue.Type().Elem()
 
-	readRange := fmt.Sprintf("%s!A:Z", q.sheetName)
-	resp, err := q.client.service.Spreadsheets.Values.Get(q.client.spreadsheetID, readRange).Do()
+	headers, rows, err := q.run(exec, nil)
+	if err != nil {
+		return err
+	}
+
+	fieldMap := make(map[string]int)
+	for i, header := range headers {
+		fieldMap[header] = i
+	}
+
+	for _, row := range rows {
+		elem := reflect.New(elemType).Elem()
+		if err := q.mapRowToStruct(row, headers, fieldMap, elem); err != nil {
+			return fmt.Errorf("failed to map row to struct: %w", err)
+		}
+
+		sliceValue.Set(reflect.Append(sliceValue, elem))
+	}
+
+	return nil
+}
+
+// rows reads the sheet and returns the result columns together with the
+// projected rows that match the query, after OFFSET and LIMIT have been
+// applied.
+func (q *Query) rows() ([]string, [][]interface{}, error) {
+	return q.run(newExecution(q.client), nil)
+}
+
+// run executes the query within exec. outer is the row of the enclosing query
+// when q is a subquery, and nil otherwise.
+func (q *Query) run(exec *execution, outer *evalEnv) ([]string, [][]interface{}, error) {
+	if q.err != nil {
+		return nil, nil, q.err
+	}
+
+	tables, err := q.readTables(exec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tables[0].headers == nil {
+		if len(q.setOps) > 0 {
+			return q.combine(exec, outer, nil, nil)
+		}
+		return nil, nil, nil
+	}
+
+	headers, fieldMap := joinHeaders(tables)
+	if outer != nil {
+		for name := range outer.fieldMap {
+			if _, exists := fieldMap[name]; !exists {
+				fieldMap[name] = outerColumn
+			}
+		}
+	}
+	base := &evalEnv{query: q, fieldMap: fieldMap, exec: exec, outer: outer}
+
+	columns, err := q.resultColumns(headers, fieldMap)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := tables[0].rows
+	if len(q.joins) > 0 {
+		if values, err = q.joinRows(tables, base); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if containsAggregate(q.where) {
+		return nil, nil, fmt.Errorf("aggregate functions are not allowed in WHERE")
+	}
+
+	var matched [][]interface{}
+	for _, row := range values {
+		matches, err := q.matches(base.with(row))
+		if err != nil {
+			return nil, nil, err
+		}
+		if matches {
+			matched = append(matched, row)
+		}
+	}
+
+	envs, err := q.groupRows(matched, base)
 	if err != nil {
-		return fmt.Errorf("failed to read sheet: %w", err)
+		return nil, nil, err
+	}
+	if err := q.computeWindows(envs, fieldMap); err != nil {
+		return nil, nil, err
+	}
+
+	// With set operations, ORDER BY, OFFSET and LIMIT apply to the combined
+	// rows instead.
+	compound := len(q.setOps) > 0
+	if !compound {
+		if q.distinct {
+			if err := q.checkDistinctOrder(fieldMap); err != nil {
+				return nil, nil, err
+			}
+		}
+		if err := q.sortRows(envs, fieldMap); err != nil {
+			return nil, nil, err
+		}
+	}
+	if !compound && !q.distinct {
+		envs = paginate(envs, q.offset, q.limit)
+	}
+
+	var rows [][]interface{}
+	for _, env := range envs {
+		projected, err := q.project(env, headers)
+		if err != nil {
+			return nil, nil, err
+		}
+		rows = append(rows, projected)
 	}
 
-	if len(resp.Values) == 0 {
+	if q.distinct {
+		rows = distinctRows(rows)
+	}
+	if compound {
+		return q.combine(exec, outer, columns, rows)
+	}
+	if q.distinct {
+		rows = paginate(rows, q.offset, q.limit)
+	}
+
+	return columns, rows, nil
+}
+
+// paginate returns the items left after skipping offset and keeping at most
+// limit, where 0 means no limit.
+func paginate[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
 		return nil
 	}
+	items = items[offset:]
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
 
-	headers := make([]string, len(resp.Values[0]))
-	for i, header := range resp.Values[0] {
-		headers[i] = fmt.Sprintf("%v", header)
+// sortRows orders result rows by the ORDER BY keys. Rows that compare equal
+// keep their sheet order.
+func (q *Query) sortRows(envs []*evalEnv, fieldMap map[string]int) error {
+	if len(q.orderBy) == 0 {
+		return nil
 	}
 
-	fieldMap := make(map[string]int)
-	for i, header := range headers {
-		fieldMap[header] = i
+	items := make([]orderItem, len(q.orderBy))
+	for i, item := range q.orderBy {
+		item.expr = q.resolveAliases(item.expr, fieldMap)
+		if err := checkColumns(item.expr, fieldMap, "ORDER BY"); err != nil {
+			return err
+		}
+		items[i] = item
 	}
 
-	for rowIndex, row := range resp.Values[1:] {
-		if !q.matchesWhere(row, headers, fieldMap) {
-			continue
+	_, err := sortEnvs(envs, items)
+	return err
+}
+
+// sortEnvs stably sorts envs by items and returns the sort keys of the
+// sorted rows.
+func sortEnvs(envs []*evalEnv, items []orderItem) ([][]interface{}, error) {
+	// Evaluate every key once up front rather than on each comparison.
+	keys := make([][]interface{}, len(envs))
+	for i, env := range envs {
+		keys[i] = make([]interface{}, len(items))
+		for j, item := range items {
+			value, err := item.expr.eval(env)
+			if err != nil {
+				return nil, err
+			}
+			keys[i][j] = value
 		}
+	}
 
-		if q.offset > 0 && rowIndex < q.offset {
-			continue
+	index := make([]int, len(envs))
+	for i := range index {
+		index[i] = i
+	}
+
+	sort.SliceStable(index, func(a, b int) bool {
+		return compareKeys(keys[index[a]], keys[index[b]], items) < 0
+	})
+
+	sorted := make([]*evalEnv, len(envs))
+	sortedKeys := make([][]interface{}, len(envs))
+	for i, from := range index {
+		sorted[i], sortedKeys[i] = envs[from], keys[from]
+	}
+	copy(envs, sorted)
+
+	return sortedKeys, nil
+}
+
+// compareKeys returns the order of two rows' sort keys under items.
+func compareKeys(a, b []interface{}, items []orderItem) int {
+	for j, item := range items {
+		if c := compareSortKeys(a[j], b[j], item); c != 0 {
+			return c
 		}
+	}
+	return 0
+}
 
-		if q.limit > 0 && sliceValue.Len() >= q.limit {
-			break
+// resolveAliases lets HAVING and ORDER BY name a select list alias in place
+// of the expression it stands for. Sheet columns take precedence over
+// aliases.
+func (q *Query) resolveAliases(e expr, fieldMap map[string]int) expr {
+	return mapExpr(e, func(e expr) expr {
+		ref, ok := e.(*columnRef)
+		if !ok || ref.table != "" {
+			return e
+		}
+		if _, exists := fieldMap[ref.name]; exists {
+			return e
 		}
 
-		elem := reflect.New(elemType).Elem()
-		if err := q.mapRowToStruct(row, headers, fieldMap, elem); err != nil {
-			return fmt.Errorf("failed to map row to struct: %w", err)
+		for _, item := range q.columns {
+			if !item.star && item.alias == ref.name {
+				return item.expr
+			}
 		}
+		return e
+	})
+}
 
-		sliceValue.Set(reflect.Append(sliceValue, elem))
+// compareSortKeys returns the order of a and b under item. Values are compared
+// numerically when both are numbers and as strings otherwise, like
+// compareValues.
+func compareSortKeys(a, b interface{}, item orderItem) int {
+	aNull, bNull := isBlank(a), isBlank(b)
+	switch {
+	case aNull && bNull:
+		return 0
+	case aNull:
+		if item.nullsFirst {
+			return -1
+		}
+		return 1
+	case bNull:
+		if item.nullsFirst {
+			return 1
+		}
+		return -1
 	}
 
-	return nil
+	c := compareCells(formatValue(a), formatValue(b))
+	if item.desc {
+		return -c
+	}
+	return c
 }
 
-func (q *Query) matchesWhere(row []interface{}, headers []string, fieldMap map[string]int) bool {
-	for _, clause := range q.where {
-		colIndex, exists := fieldMap[clause.Column]
-		if !exists {
-			continue
+// isBlank reports whether v is NULL or an empty cell, which sorting and
+// aggregate functions treat alike.
+func isBlank(v interface{}) bool {
+	return v == nil || v == ""
+}
+
+// compareCells returns -1, 0 or 1 depending on whether a sorts before, with
+// or after b.
+func compareCells(a, b string) int {
+	aFloat, aErr := strconv.ParseFloat(a, 64)
+	bFloat, bErr := strconv.ParseFloat(b, 64)
+
+	if aErr == nil && bErr == nil {
+		switch {
+		case aFloat < bFloat:
+			return -1
+		case aFloat > bFloat:
+			return 1
 		}
+		return 0
+	}
 
-		if colIndex >= len(row) {
+	return strings.Compare(a, b)
+}
+
+// resultColumns returns the names of the result columns and checks that
+// every column the select list refers to exists.
+func (q *Query) resultColumns(headers []string, fieldMap map[string]int) ([]string, error) {
+	if len(q.columns) == 0 {
+		return headers, nil
+	}
+
+	var columns []string
+	for _, item := range q.columns {
+		if item.star {
+			columns = append(columns, headers...)
 			continue
 		}
 
-		cellValue := fmt.Sprintf("%v", row[colIndex])
-		expectedValue := fmt.Sprintf("%v", clause.Value)
+		if err := checkColumns(item.expr, fieldMap, "select list"); err != nil {
+			return nil, err
+		}
 
-		switch clause.Operator {
-		case "=", "==":
-			if cellValue != expectedValue {
-				return false
-			}
-		case "!=":
-			if cellValue == expectedValue {
-				return false
-			}
-		case ">":
-			if !q.compareValues(cellValue, expectedValue, ">") {
-				return false
-			}
-		case "<":
-			if !q.compareValues(cellValue, expectedValue, "<") {
-				return false
-			}
-		case ">=":
-			if !q.compareValues(cellValue, expectedValue, ">=") {
-				return false
-			}
-		case "<=":
-			if !q.compareValues(cellValue, expectedValue, "<=") {
-				return false
+		columns = append(columns, item.name())
+	}
+
+	return columns, nil
+}
+
+// checkColumns returns an error naming the first column e refers to that the
+// sheet does not have.
+func checkColumns(e expr, fieldMap map[string]int, clause string) error {
+	var err error
+	walkExpr(e, func(e expr) bool {
+		if ref, ok := e.(*columnRef); ok && err == nil {
+			if colIndex, exists := fieldMap[ref.key()]; !exists {
+				err = fmt.Errorf("unknown column %q in %s", ref.key(), clause)
+			} else if colIndex == ambiguousColumn {
+				err = fmt.Errorf("ambiguous column %q in %s", ref.key(), clause)
 			}
-		case "LIKE":
-			if !strings.Contains(strings.ToLower(cellValue), strings.ToLower(expectedValue)) {
-				return false
+		}
+		return err == nil
+	})
+	return err
+}
+
+// project evaluates the select list for one result row.
+func (q *Query) project(env *evalEnv, headers []string) ([]interface{}, error) {
+	row := env.row
+	if len(q.columns) == 0 {
+		return row, nil
+	}
+
+	var projected []interface{}
+	for _, item := range q.columns {
+		if item.star {
+			for i := range headers {
+				var value interface{}
+				if i < len(row) {
+					value = row[i]
+				}
+				projected = append(projected, value)
 			}
+			continue
+		}
+
+		value, err := item.expr.eval(env)
+		if err != nil {
+			return nil, err
 		}
+		projected = append(projected, value)
+	}
+
+	return projected, nil
+}
+
+func (q *Query) matchesWhere(row []interface{}, headers []string, fieldMap map[string]int) (bool, error) {
+	return q.matches(&evalEnv{query: q, row: row, fieldMap: fieldMap})
+}
+
+// matches reports whether the row of env satisfies the WHERE clause.
+func (q *Query) matches(env *evalEnv) (bool, error) {
+	if q.where == nil {
+		return true, nil
+	}
+
+	result, err := evalCondition(q.where, env)
+	if err != nil {
+		return false, err
+	}
+
+	return result == true, nil
+}
+
+// applyOperator reports whether left stands in the given relation to right.
+// Operators registered on the client get the values as they are; built-in
+// ones compare them as text, or as numbers when both are numeric. Where
+// rejects unknown operators, so they match nothing here.
+func (env *evalEnv) applyOperator(left interface{}, operator string, right interface{}) (bool, error) {
+	if fn := env.query.client.customOperator(operator); fn != nil {
+		return fn(left, right)
+	}
+	if fn, ok := builtinOperators[operator]; ok {
+		return fn(env, formatValue(left), formatValue(right))
 	}
-	return true
+	return false, nil
 }
 
 func (q *Query) compareValues(a, b, operator string) bool {
@@ -218,11 +920,11 @@ func (q *Query) mapRowToStruct(row []interface{}, headers []string, fieldMap map
 		}
 
 		colIndex, exists := fieldMap[tagValue]
-		if !exists || colIndex >= len(row) {
+		if !exists || colIndex >= len(row) || row[colIndex] == nil {
 			continue
 		}
 
-		cellValue := fmt.Sprintf("%v", row[colIndex])
+		cellValue := formatValue(row[colIndex])
 		if err := q.setFieldValue(fieldValue, cellValue); err != nil {
 			return fmt.Errorf("failed to set field %s: %w", field.Name, err)
 		}
@@ -232,6 +934,19 @@ func (q *Query) mapRowToStruct(row []interface{}, headers []string, fieldMap map
 }
 
 func (q *Query) setFieldValue(field reflect.Value, value string) error {
+	if field.Type() == reflect.TypeOf(time.Time{}) {
+		if value == "" {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		t, err := q.toTime(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
 	switch field.Kind() {
 	case reflect.String:
 		field.SetString(value)
@@ -283,17 +998,17 @@ func (q *Query) Insert(data interface{}) error {
 	}
 
 	readRange := fmt.Sprintf("%s!1:1", q.sheetName)
-	resp, err := q.client.service.Spreadsheets.Values.Get(q.client.spreadsheetID, readRange).Do()
+	values, err := q.client.backend.ReadRange(q.client.spreadsheetID, readRange)
 	if err != nil {
 		return fmt.Errorf("failed to read headers: %w", err)
 	}
 
-	if len(resp.Values) == 0 {
+	if len(values) == 0 {
 		return fmt.Errorf("no headers found in sheet")
 	}
 
-	headers := make([]string, len(resp.Values[0]))
-	for i, header := range resp.Values[0] {
+	headers := make([]string, len(values[0]))
+	for i, header := range values[0] {
 		headers[i] = fmt.Sprintf("%v", header)
 	}
 
@@ -323,44 +1038,150 @@ func (q *Query) Insert(data interface{}) error {
 	}
 
 	writeRange := fmt.Sprintf("%s!A:Z", q.sheetName)
-	valueRange := &sheets.ValueRange{
-		Values: [][]interface{}{row},
+	err = q.client.backend.AppendRows(q.client.spreadsheetID, writeRange, [][]interface{}{row})
+	if err != nil {
+		return fmt.Errorf("failed to insert row: %w", err)
 	}
 
-	_, err = q.client.service.Spreadsheets.Values.Append(q.client.spreadsheetID, writeRange, valueRange).
-		ValueInputOption("RAW").
-		InsertDataOption("INSERT_ROWS").
-		Do()
+	return nil
+}
 
+// insertRows appends the rows of an INSERT ... VALUES or INSERT ... SELECT
+// statement in a single request and returns how many it added. Without a
+// column list, VALUES fill the sheet's columns in order and the columns of
+// source are matched to the sheet's by name.
+func (q *Query) insertRows(exec *execution, columns []string, values [][]expr, source *Query) (int, error) {
+	headerRange := fmt.Sprintf("%s!1:1", q.sheetName)
+	readRanges := []string{headerRange}
+	if source != nil {
+		readRanges = append(readRanges, source.sheetRanges()...)
+	}
+	sheets, err := exec.read(readRanges)
 	if err != nil {
-		return fmt.Errorf("failed to insert row: %w", err)
+		return 0, err
 	}
 
-	return nil
+	if len(sheets[0]) == 0 {
+		return 0, fmt.Errorf("no headers found in sheet")
+	}
+
+	headers := make([]string, len(sheets[0][0]))
+	fieldMap := make(map[string]int)
+	for i, header := range sheets[0][0] {
+		headers[i] = fmt.Sprintf("%v", header)
+		fieldMap[headers[i]] = i
+	}
+
+	var rows [][]interface{}
+	if source != nil {
+		var sourceColumns []string
+		if sourceColumns, rows, err = source.run(exec, nil); err != nil {
+			return 0, err
+		}
+		if columns == nil {
+			columns = sourceColumns
+		} else if len(sourceColumns) != len(columns) {
+			return 0, fmt.Errorf("INSERT has %d columns but SELECT returns %d", len(columns), len(sourceColumns))
+		}
+	} else {
+		if columns == nil {
+			columns = headers
+		}
+		env := &evalEnv{query: q, fieldMap: map[string]int{}, exec: exec}
+		for _, exprs := range values {
+			if len(exprs) > len(columns) {
+				return 0, fmt.Errorf("VALUES has %d values for %d columns", len(exprs), len(columns))
+			}
+			row := make([]interface{}, len(exprs))
+			for i, e := range exprs {
+				if row[i], err = e.eval(env); err != nil {
+					return 0, err
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	indexes := make([]int, len(columns))
+	for i, column := range columns {
+		index, exists := fieldMap[column]
+		if !exists {
+			return 0, fmt.Errorf("unknown column %q in INSERT", column)
+		}
+		indexes[i] = index
+	}
+
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	cells := make([][]interface{}, len(rows))
+	for r, row := range rows {
+		cells[r] = make([]interface{}, len(headers))
+		for i, value := range row {
+			cells[r][indexes[i]] = cellValue(value)
+		}
+	}
+
+	writeRange := fmt.Sprintf("%s!A:Z", q.sheetName)
+	if err := q.client.backend.AppendRows(q.client.spreadsheetID, writeRange, cells); err != nil {
+		return 0, fmt.Errorf("failed to insert rows: %w", err)
+	}
+
+	return len(cells), nil
 }
 
 func (q *Query) Update(data interface{}) error {
+	return q.updateMatching(newExecution(q.client), data, nil)
+}
+
+// updateMatching is Update within exec, also applying the assignments of an
+// UPDATE statement.
+func (q *Query) updateMatching(exec *execution, data interface{}, assignments []assignment) error {
+	updatedRows, err := q.update(exec, data, assignments)
+	if err != nil {
+		return err
+	}
+
+	if updatedRows == 0 {
+		return fmt.Errorf("no rows matched the where conditions")
+	}
+
+	return nil
+}
+
+// update changes every matching row and returns how many rows it changed.
+// The columns of data, if it is not nil, are written first, then the
+// assignments, which are all evaluated against the row as it was.
+func (q *Query) update(exec *execution, data interface{}, assignments []assignment) (int, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+	if len(q.joins) > 0 {
+		return 0, fmt.Errorf("cannot update a query with joins")
+	}
+
 	dataValue := reflect.ValueOf(data)
 	if dataValue.Kind() == reflect.Ptr {
 		dataValue = dataValue.Elem()
 	}
 
-	if dataValue.Kind() != reflect.Struct {
-		return fmt.Errorf("data must be a struct or pointer to struct")
+	if (data != nil || len(assignments) == 0) && dataValue.Kind() != reflect.Struct {
+		return 0, fmt.Errorf("data must be a struct or pointer to struct")
 	}
 
 	readRange := fmt.Sprintf("%s!A:Z", q.sheetName)
-	resp, err := q.client.service.Spreadsheets.Values.Get(q.client.spreadsheetID, readRange).Do()
+	values, err := q.client.backend.ReadRange(q.client.spreadsheetID, readRange)
 	if err != nil {
-		return fmt.Errorf("failed to read sheet: %w", err)
+		return 0, fmt.Errorf("failed to read sheet: %w", err)
 	}
 
-	if len(resp.Values) == 0 {
-		return fmt.Errorf("no data found in sheet")
+	if len(values) == 0 {
+		return 0, fmt.Errorf("no data found in sheet")
 	}
 
-	headers := make([]string, len(resp.Values[0]))
-	for i, header := range resp.Values[0] {
+	headers := make([]string, len(values[0]))
+	for i, header := range values[0] {
 		headers[i] = fmt.Sprintf("%v", header)
 	}
 
@@ -369,70 +1190,123 @@ func (q *Query) Update(data interface{}) error {
 		fieldMap[header] = i
 	}
 
+	for _, a := range assignments {
+		if _, exists := fieldMap[a.column]; !exists {
+			return 0, fmt.Errorf("unknown column %q in SET", a.column)
+		}
+	}
+
+	base := &evalEnv{query: q, fieldMap: fieldMap, exec: exec}
+
 	updatedRows := 0
-	for rowIndex, row := range resp.Values[1:] {
-		if !q.matchesWhere(row, headers, fieldMap) {
+	for rowIndex, row := range values[1:] {
+		env := base.with(row)
+		matches, err := q.matches(env)
+		if err != nil {
+			return updatedRows, err
+		}
+		if !matches {
 			continue
 		}
 
+		assigned := make([]interface{}, len(assignments))
+		for i, a := range assignments {
+			if assigned[i], err = a.value.eval(env); err != nil {
+				return updatedRows, fmt.Errorf("SET %s: %w", quoteIdentifier(a.column), err)
+			}
+		}
+
 		actualRowIndex := rowIndex + 2
 		updatedRow := make([]interface{}, len(headers))
 		copy(updatedRow, row)
 
-		dataType := dataValue.Type()
-		for i := 0; i < dataType.NumField(); i++ {
-			field := dataType.Field(i)
-			fieldValue := dataValue.Field(i)
+		if data != nil {
+			dataType := dataValue.Type()
+			for i := 0; i < dataType.NumField(); i++ {
+				field := dataType.Field(i)
+				fieldValue := dataValue.Field(i)
 
-			tagValue := field.Tag.Get("sheet")
-			if tagValue == "" {
-				tagValue = field.Name
-			}
+				tagValue := field.Tag.Get("sheet")
+				if tagValue == "" {
+					tagValue = field.Name
+				}
 
-			colIndex, exists := fieldMap[tagValue]
-			if !exists {
-				continue
-			}
+				colIndex, exists := fieldMap[tagValue]
+				if !exists {
+					continue
+				}
 
-			updatedRow[colIndex] = fieldValue.Interface()
+				updatedRow[colIndex] = fieldValue.Interface()
+			}
 		}
 
-		updateRange := fmt.Sprintf("%s!A%d:Z%d", q.sheetName, actualRowIndex, actualRowIndex)
-		valueRange := &sheets.ValueRange{
-			Values: [][]interface{}{updatedRow},
+		for i, a := range assignments {
+			updatedRow[fieldMap[a.column]] = cellValue(assigned[i])
 		}
 
-		_, err = q.client.service.Spreadsheets.Values.Update(q.client.spreadsheetID, updateRange, valueRange).
-			ValueInputOption("RAW").
-			Do()
-
+		updateRange := fmt.Sprintf("%s!A%d:Z%d", q.sheetName, actualRowIndex, actualRowIndex)
+		err = q.client.backend.UpdateRange(q.client.spreadsheetID, updateRange, [][]interface{}{updatedRow})
 		if err != nil {
-			return fmt.Errorf("failed to update row %d: %w", actualRowIndex, err)
+			return updatedRows, fmt.Errorf("failed to update row %d: %w", actualRowIndex, err)
 		}
 
 		updatedRows++
 	}
 
-	if updatedRows == 0 {
+	return updatedRows, nil
+}
+
+// cellValue converts the result of an expression to a value to write to a
+// cell: NULL clears the cell and times are written as text.
+func cellValue(v interface{}) interface{} {
+	switch v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return formatValue(v)
+	}
+	return v
+}
+
+func (q *Query) Delete() error {
+	return q.deleteMatching(newExecution(q.client))
+}
+
+// deleteMatching is Delete within exec.
+func (q *Query) deleteMatching(exec *execution) error {
+	deletedRows, err := q.delete(exec)
+	if err != nil {
+		return err
+	}
+
+	if deletedRows == 0 {
 		return fmt.Errorf("no rows matched the where conditions")
 	}
 
 	return nil
 }
 
-func (q *Query) Delete() error {
+// delete removes every matching row and returns how many rows it removed.
+func (q *Query) delete(exec *execution) (int, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+	if len(q.joins) > 0 {
+		return 0, fmt.Errorf("cannot delete from a query with joins")
+	}
+
 	readRange := fmt.Sprintf("%s!A:Z", q.sheetName)
-	resp, err := q.client.service.Spreadsheets.Values.Get(q.client.spreadsheetID, readRange).Do()
+	values, err := q.client.backend.ReadRange(q.client.spreadsheetID, readRange)
 	if err != nil {
-		return fmt.Errorf("failed to read sheet: %w", err)
+		return 0, fmt.Errorf("failed to read sheet: %w", err)
 	}
 
-	if len(resp.Values) == 0 {
-		return fmt.Errorf("no data found in sheet")
+	if len(values) == 0 {
+		return 0, fmt.Errorf("no data found in sheet")
 	}
 
-	headers := make([]string, len(resp.Values[0]))
-	for i, header := range resp.Values[0] {
+	headers := make([]string, len(values[0]))
+	for i, header := range values[0] {
 		headers[i] = fmt.Sprintf("%v", header)
 	}
 
@@ -441,18 +1315,21 @@ func (q *Query) Delete() error {
 		fieldMap[header] = i
 	}
 
+	base := &evalEnv{query: q, fieldMap: fieldMap, exec: exec}
+
 	var rowsToDelete []int
-	for rowIndex, row := range resp.Values[1:] {
-		if q.matchesWhere(row, headers, fieldMap) {
+	for rowIndex, row := range values[1:] {
+		matches, err := q.matches(base.with(row))
+		if err != nil {
+			return 0, err
+		}
+		if matches {
 			actualRowIndex := rowIndex + 2
 			rowsToDelete = append(rowsToDelete, actualRowIndex)
 		}
 	}
 
-	if len(rowsToDelete) == 0 {
-		return fmt.Errorf("no rows matched the where conditions")
-	}
-
+	deletedRows := 0
 	for i := len(rowsToDelete) - 1; i >= 0; i-- {
 		rowIndex := rowsToDelete[i]
 
@@ -471,24 +1348,26 @@ func (q *Query) Delete() error {
 			},
 		}
 
-		_, err = q.client.service.Spreadsheets.BatchUpdate(q.client.spreadsheetID, batchUpdateRequest).Do()
+		err = q.client.backend.BatchUpdate(q.client.spreadsheetID, batchUpdateRequest)
 		if err != nil {
-			return fmt.Errorf("failed to delete row %d: %w", rowIndex, err)
+			return deletedRows, fmt.Errorf("failed to delete row %d: %w", rowIndex, err)
 		}
+
+		deletedRows++
 	}
 
-	return nil
+	return deletedRows, nil
 }
 
 func (q *Query) getSheetId() int64 {
-	resp, err := q.client.service.Spreadsheets.Get(q.client.spreadsheetID).Do()
+	props, err := q.client.backend.SheetProperties(q.client.spreadsheetID)
 	if err != nil {
 		return 0
 	}
 
-	for _, sheet := range resp.Sheets {
-		if sheet.Properties.Title == q.sheetName {
-			return sheet.Properties.SheetId
+	for _, prop := range props {
+		if prop.Title == q.sheetName {
+			return prop.SheetId
 		}
 	}
 
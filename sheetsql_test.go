@@ -4,6 +4,8 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+
+	"google.golang.org/api/sheets/v4"
 )
 
 func TestQuery_Where(t *testing.T) {
@@ -13,16 +15,171 @@ func TestQuery_Where(t *testing.T) {
 	query.Where("Name", "=", "John")
 	query.Where("Age", ">", 18)
 
-	if len(query.where) != 2 {
-		t.Errorf("Expected 2 where clauses, got %d", len(query.where))
+	cond, ok := query.where.(*logicalExpr)
+	if !ok || cond.op != "AND" {
+		t.Fatalf("Expected 2 where clauses joined by AND, got %v", query.where)
+	}
+
+	first, ok := cond.left.(*WhereClause)
+	if !ok || first.Column != "Name" || first.Operator != "=" || first.Value != "John" {
+		t.Errorf("First where clause incorrect: %+v", cond.left)
+	}
+
+	second, ok := cond.right.(*WhereClause)
+	if !ok || second.Column != "Age" || second.Operator != ">" || second.Value != 18 {
+		t.Errorf("Second where clause incorrect: %+v", cond.right)
+	}
+}
+
+func TestQuery_WhereGroups(t *testing.T) {
+	client := &Client{}
+
+	tests := []struct {
+		name     string
+		build    func(q *Query)
+		expected string
+	}{
+		{
+			name: "or where",
+			build: func(q *Query) {
+				q.Where("Status", "=", "open").OrWhere("Status", "=", "pending")
+			},
+			expected: "Status = 'open' OR Status = 'pending'",
+		},
+		{
+			name: "and binds before or",
+			build: func(q *Query) {
+				q.Where("A", "=", 1).Where("B", "=", 2).OrWhere("C", "=", 3)
+			},
+			expected: "A = 1 AND B = 2 OR C = 3",
+		},
+		{
+			name: "where group",
+			build: func(q *Query) {
+				q.Where("Age", ">", 18).WhereGroup(func(g *Query) {
+					g.Where("City", "=", "Boston").OrWhere("City", "=", "Chicago")
+				})
+			},
+			expected: "Age > 18 AND (City = 'Boston' OR City = 'Chicago')",
+		},
+		{
+			name: "or where group",
+			build: func(q *Query) {
+				q.Where("Admin", "=", true).OrWhereGroup(func(g *Query) {
+					g.Where("Age", ">", 18).Where("Verified", "=", true)
+				})
+			},
+			expected: "Admin = TRUE OR Age > 18 AND Verified = TRUE",
+		},
+		{
+			name: "where not",
+			build: func(q *Query) {
+				q.WhereNot(func(g *Query) {
+					g.Where("City", "=", "Boston").OrWhere("City", "=", "Chicago")
+				})
+			},
+			expected: "NOT (City = 'Boston' OR City = 'Chicago')",
+		},
+		{
+			name: "empty group",
+			build: func(q *Query) {
+				q.Where("Age", ">", 18).WhereGroup(func(g *Query) {})
+			},
+			expected: "Age > 18",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := client.From("TestSheet")
+			tt.build(query)
+
+			if query.where.String() != tt.expected {
+				t.Errorf("where = %s, expected %s", query.where, tt.expected)
+			}
+		})
+	}
+}
+
+func TestQuery_WhereOperators(t *testing.T) {
+	mock := NewMockSheetsService()
+	mock.AddSheetData("Tasks", [][]interface{}{
+		{"Name", "Owner", "Points"},
+		{"Ann", "sam", "3"},
+		{"Ben", "", "5"},
+		{"Cat"},
+		{"Dan", "kim", "8"},
+	})
+	mock.AddSheetData("Owners", [][]interface{}{
+		{"Team", "Owner"},
+		{"red", "sam"},
+		{"blue", "kim"},
+		{"green"},
+	})
+	client := mock.Client("test-id")
+
+	tests := []struct {
+		name     string
+		query    *Query
+		expected []string
+	}{
+		{"in", client.From("Tasks").Where("Owner", "IN", []string{"sam", "kim"}), []string{"Ann", "Dan"}},
+		{"not in", client.From("Tasks").Where("Owner", "not in", []string{"sam"}), []string{"Ben", "Dan"}},
+		{"in subquery", client.From("Tasks").Where("Owner", "IN", client.From("Tasks").Select("Owner").Where("Points", ">", 5)), []string{"Dan"}},
+		{"in subquery on another sheet", client.From("Tasks").Where("Owner", "IN", client.From("Owners").Where("Team", "=", "blue").Select("Owner")), []string{"Dan"}},
+		{"in correlated subquery", client.From("Tasks").Where("Owner", "IN", client.From("Owners").Where("Name", "=", "Dan").Select("Owner")), []string{"Dan"}},
+		{"not in subquery with NULL", client.From("Tasks").Where("Owner", "NOT IN", client.From("Owners").Select("Owner")), nil},
+		{"not in subquery without NULL", client.From("Tasks").Where("Owner", "NOT IN", client.From("Owners").Where("Team", "=", "red").Select("Owner")), []string{"Ben", "Dan"}},
+		{"between", client.From("Tasks").Where("Points", "BETWEEN", []int{3, 5}), []string{"Ann", "Ben"}},
+		{"not between", client.From("Tasks").Where("Points", "NOT BETWEEN", []int{3, 5}), []string{"Dan"}},
+		{"is null", client.From("Tasks").Where("Owner", "IS NULL", nil), []string{"Cat"}},
+		{"is not null", client.From("Tasks").Where("Owner", "IS NOT NULL", nil), []string{"Ann", "Ben", "Dan"}},
+		{"not like", client.From("Tasks").Where("Name", "NOT LIKE", "%a%"), []string{"Ann", "Ben"}},
+		{"not equal", client.From("Tasks").Where("Name", "<>", "Ann"), []string{"Ben", "Cat", "Dan"}},
 	}
 
-	if query.where[0].Column != "Name" || query.where[0].Operator != "=" || query.where[0].Value != "John" {
-		t.Errorf("First where clause incorrect: %+v", query.where[0])
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rows, err := tt.query.Select("Name").rows()
+			if err != nil {
+				t.Fatalf("rows() error = %v", err)
+			}
+			var names []string
+			for _, row := range rows {
+				names = append(names, row[0].(string))
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("rows = %v, expected %v", names, tt.expected)
+			}
+		})
 	}
 
-	if query.where[1].Column != "Age" || query.where[1].Operator != ">" || query.where[1].Value != 18 {
-		t.Errorf("Second where clause incorrect: %+v", query.where[1])
+	errorTests := []struct {
+		name     string
+		query    *Query
+		expected string
+	}{
+		{"unknown operator", client.From("Tasks").Where("Owner", "~=", "sam"), `unknown operator "~="`},
+		{"unknown operator in group", client.From("Tasks").WhereGroup(func(g *Query) { g.Where("Owner", "IS", "sam") }), `unknown operator "IS"`},
+		{"between one value", client.From("Tasks").Where("Points", "BETWEEN", []int{3}), "BETWEEN requires a slice of two values, got [3]"},
+		{"in scalar", client.From("Tasks").Where("Owner", "IN", "sam"), "IN requires a slice or *Query value, got string"},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tt.query.rows()
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("rows() error = %v, expected %q", err, tt.expected)
+			}
+		})
+	}
+
+	err := client.From("Tasks").Where("Owner", "~=", "sam").Delete()
+	if err == nil || err.Error() != `unknown operator "~="` {
+		t.Errorf("Delete() error = %v, expected unknown operator", err)
+	}
+	if values, _ := mock.ReadRange("test-id", "Tasks!A:Z"); len(values) != 5 {
+		t.Errorf("Delete() with an unknown operator removed rows: %v", values)
 	}
 }
 
@@ -43,7 +200,6 @@ func TestQuery_LimitOffset(t *testing.T) {
 
 func TestQuery_matchesWhere(t *testing.T) {
 	client := &Client{}
-	query := client.From("TestSheet")
 
 	headers := []string{"Name", "Age", "City"}
 	fieldMap := map[string]int{"Name": 0, "Age": 1, "City": 2}
@@ -85,8 +241,8 @@ func TestQuery_matchesWhere(t *testing.T) {
 			expected: false,
 		},
 		{
-			name:     "like match",
-			where:    []WhereClause{{Column: "City", Operator: "LIKE", Value: "ny"}},
+			name:     "contains match",
+			where:    []WhereClause{{Column: "City", Operator: "CONTAINS", Value: "ny"}},
 			row:      []interface{}{"John", "25", "NYC"},
 			expected: true,
 		},
@@ -112,8 +268,15 @@ func TestQuery_matchesWhere(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			query.where = tt.where
-			result := query.matchesWhere(tt.row, headers, fieldMap)
+			query := client.From("TestSheet")
+			for _, clause := range tt.where {
+				query.Where(clause.Column, clause.Operator, clause.Value)
+			}
+
+			result, err := query.matchesWhere(tt.row, headers, fieldMap)
+			if err != nil {
+				t.Fatalf("matchesWhere() error = %v", err)
+			}
 			if result != tt.expected {
 				t.Errorf("matchesWhere() = %v, expected %v", result, tt.expected)
 			}
@@ -370,3 +533,254 @@ func isAPIError(err error) bool {
 		strings.Contains(errStr, "no rows matched the where conditions") ||
 		errStr == "data must be a struct or pointer to struct"
 }
+
+type stubBackend struct {
+	values   [][]interface{}
+	appended [][]interface{}
+	updated  map[string][]interface{}
+	deleted  []int64
+}
+
+func (b *stubBackend) ReadRange(spreadsheetID, readRange string) ([][]interface{}, error) {
+	return b.values, nil
+}
+
+func (b *stubBackend) ReadRanges(spreadsheetID string, readRanges []string) ([][][]interface{}, error) {
+	values := make([][][]interface{}, len(readRanges))
+	for i := range readRanges {
+		values[i] = b.values
+	}
+	return values, nil
+}
+
+func (b *stubBackend) AppendRows(spreadsheetID, writeRange string, rows [][]interface{}) error {
+	b.appended = append(b.appended, rows...)
+	return nil
+}
+
+func (b *stubBackend) UpdateRange(spreadsheetID, updateRange string, rows [][]interface{}) error {
+	if b.updated == nil {
+		b.updated = make(map[string][]interface{})
+	}
+	b.updated[updateRange] = rows[0]
+	return nil
+}
+
+func (b *stubBackend) BatchUpdate(spreadsheetID string, req *sheets.BatchUpdateSpreadsheetRequest) error {
+	for _, r := range req.Requests {
+		if r.DeleteDimension != nil {
+			b.deleted = append(b.deleted, r.DeleteDimension.Range.StartIndex)
+		}
+	}
+	return nil
+}
+
+func (b *stubBackend) SheetProperties(spreadsheetID string) ([]*sheets.SheetProperties, error) {
+	return []*sheets.SheetProperties{{Title: "TestSheet", SheetId: 7}}, nil
+}
+
+func TestQuery_Backend(t *testing.T) {
+	backend := &stubBackend{
+		values: [][]interface{}{
+			{"Name", "Age", "City"},
+			{"John", "25", "NYC"},
+			{"Jane", "31", "LA"},
+		},
+	}
+	client := NewClientWithBackend("test-id", backend)
+
+	type Person struct {
+		Name string `sheet:"Name"`
+		Age  int    `sheet:"Age"`
+		City string `sheet:"City"`
+	}
+
+	var people []Person
+	if err := client.From("TestSheet").Where("Age", ">", 30).Get(&people); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(people) != 1 || people[0].Name != "Jane" {
+		t.Errorf("Get() = %+v, expected only Jane", people)
+	}
+
+	if err := client.From("TestSheet").Insert(Person{Name: "Bob", Age: 40, City: "SF"}); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	if !reflect.DeepEqual(backend.appended, [][]interface{}{{"Bob", 40, "SF"}}) {
+		t.Errorf("Insert() appended %v", backend.appended)
+	}
+
+	if err := client.From("TestSheet").Where("Name", "=", "John").Update(Person{Name: "John", Age: 26, City: "NYC"}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, ok := backend.updated["TestSheet!A2:Z2"]; !ok {
+		t.Errorf("Update() wrote %v, expected TestSheet!A2:Z2", backend.updated)
+	}
+
+	if err := client.From("TestSheet").Where("Name", "=", "Jane").Delete(); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if !reflect.DeepEqual(backend.deleted, []int64{2}) {
+		t.Errorf("Delete() removed start indexes %v, expected [2]", backend.deleted)
+	}
+
+	if id := client.From("TestSheet").getSheetId(); id != 7 {
+		t.Errorf("getSheetId() = %d, expected 7", id)
+	}
+}
+
+func TestQuery_Select(t *testing.T) {
+	client := SetupTestData().Client("test-id")
+
+	columns, rows, err := client.From("Users").Select("Name", "City").Where("ID", "=", 3).rows()
+	if err != nil {
+		t.Fatalf("rows() error = %v", err)
+	}
+	if !reflect.DeepEqual(columns, []string{"Name", "City"}) {
+		t.Errorf("columns = %v, expected [Name City]", columns)
+	}
+	if !reflect.DeepEqual(rows, [][]interface{}{{"Bob Johnson", "Chicago"}}) {
+		t.Errorf("rows = %v", rows)
+	}
+
+	type User struct {
+		Name string `sheet:"Name"`
+		Age  int    `sheet:"Age"`
+	}
+
+	var users []User
+	if err := client.From("Users").Select("Name").Limit(1).Get(&users); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(users) != 1 || users[0].Name != "John Doe" || users[0].Age != 0 {
+		t.Errorf("Get() = %+v, expected John Doe without an age", users)
+	}
+
+	err = client.From("Users").Select("Name", "Phone").Get(&users)
+	if err == nil || !strings.Contains(err.Error(), `unknown column "Phone"`) {
+		t.Errorf("Get() error = %v, expected unknown column", err)
+	}
+}
+
+func TestQuery_OrderBy(t *testing.T) {
+	mock := NewMockSheetsService()
+	mock.AddSheetData("Scores", [][]interface{}{
+		{"Name", "Team", "Score"},
+		{"Ann", "red", "9"},
+		{"Ben", "blue", "10"},
+		{"Cat", "red"},
+		{"Dan", "blue", "2"},
+		{"Eve", "red", "10"},
+	})
+	client := mock.Client("test-id")
+
+	names := func(q *Query) []string {
+		t.Helper()
+		_, rows, err := q.rows()
+		if err != nil {
+			t.Fatalf("rows() error = %v", err)
+		}
+		var names []string
+		for _, row := range rows {
+			names = append(names, row[0].(string))
+		}
+		return names
+	}
+
+	tests := []struct {
+		name     string
+		query    *Query
+		expected []string
+	}{
+		{"numeric ascending", client.From("Scores").OrderBy("Score", ""), []string{"Dan", "Ann", "Ben", "Eve", "Cat"}},
+		{"descending", client.From("Scores").OrderBy("Score", "desc"), []string{"Cat", "Ben", "Eve", "Ann", "Dan"}},
+		{"nulls first", client.From("Scores").OrderBy("Score", "ASC NULLS FIRST"), []string{"Cat", "Dan", "Ann", "Ben", "Eve"}},
+		{"nulls last", client.From("Scores").OrderBy("Score", "DESC NULLS LAST"), []string{"Ben", "Eve", "Ann", "Dan", "Cat"}},
+		{"two keys", client.From("Scores").OrderBy("Team", "ASC").OrderBy("Name", "DESC"), []string{"Dan", "Ben", "Eve", "Cat", "Ann"}},
+		{"top n", client.From("Scores").OrderBy("Score", "DESC NULLS LAST").Limit(2), []string{"Ben", "Eve"}},
+		{"offset after sort", client.From("Scores").Where("Team", "=", "red").OrderBy("Name", "DESC").Offset(1), []string{"Cat", "Ann"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := names(tt.query); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("rows = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+
+	_, _, err := client.From("Scores").OrderBy("Score", "UPWARDS").rows()
+	if err == nil || err.Error() != `invalid sort direction "UPWARDS"` {
+		t.Errorf("rows() error = %v, expected invalid sort direction", err)
+	}
+
+	_, _, err = client.From("Scores").OrderBy("Rank", "").rows()
+	if err == nil || err.Error() != `unknown column "Rank" in ORDER BY` {
+		t.Errorf("rows() error = %v, expected unknown column", err)
+	}
+}
+
+func TestQuery_GroupBy(t *testing.T) {
+	client := SetupTestData().Client("test-id")
+
+	type CityStats struct {
+		City   string  `sheet:"City"`
+		Users  int     `sheet:"Users"`
+		AvgAge float64 `sheet:"AvgAge"`
+	}
+
+	var stats []CityStats
+	err := client.From("Users").
+		GroupBy("City").
+		Aggregate("COUNT", "*", "Users").
+		Aggregate("AVG", "Age", "AvgAge").
+		Having("Users", ">", 1).
+		Get(&stats)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	expected := []CityStats{{City: "New York", Users: 2, AvgAge: 29}}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("Get() = %+v, expected %+v", stats, expected)
+	}
+
+	columns, rows, err := client.From("Users").
+		Aggregate("COUNT DISTINCT", "City", "Cities").
+		Aggregate("MAX", "Age", "Oldest").
+		rows()
+	if err != nil {
+		t.Fatalf("rows() error = %v", err)
+	}
+	if !reflect.DeepEqual(columns, []string{"Cities", "Oldest"}) || !reflect.DeepEqual(rows, [][]interface{}{{4, 35}}) {
+		t.Errorf("rows() = %v %v, expected [Cities Oldest] [[4 35]]", columns, rows)
+	}
+
+	_, _, err = client.From("Users").Aggregate("MEDIAN", "Age", "m").rows()
+	if err == nil || err.Error() != `unknown aggregate function "MEDIAN"` {
+		t.Errorf("rows() error = %v, expected unknown aggregate function", err)
+	}
+}
+
+func TestQuery_String(t *testing.T) {
+	parser := NewSQLParser(&Client{})
+
+	tests := []string{
+		"SELECT * FROM Users",
+		"SELECT Name AS `Full Name`, COUNT(*) FROM Users u LEFT JOIN Orders o ON o.UserID = u.ID WHERE Age > 18 GROUP BY Name HAVING COUNT(*) > 1 ORDER BY Name DESC NULLS LAST LIMIT 5 OFFSET 2",
+		"SELECT Name FROM Users WHERE ID IN (SELECT UserID FROM Orders) AND NOT EXISTS (SELECT * FROM Bans WHERE Bans.ID = Users.ID)",
+		"SELECT UPPER(Name) AS n, (Age + 1) * 2 - Age % 3, -Age, CASE WHEN Age > 18 THEN 'adult' ELSE CONCAT('minor ', Age) END, CAST(Joined AS DATE) FROM Users WHERE Age - 1 BETWEEN 1 + 1 AND 10 ORDER BY LENGTH(Name) DESC",
+		"SELECT * FROM Users WHERE Age NOT BETWEEN 18 AND 65 OR City NOT IN ('Boston', 'Chicago') OR Email IS NULL OR Name NOT LIKE 'a!%' ESCAPE '!'",
+		"SELECT EXTRACT(YEAR FROM Joined), DATE_TRUNC('month', Joined) FROM Users WHERE Joined >= DATE '2024-01-01' AND Joined < TIMESTAMP '2024-06-01 12:30:00'",
+	}
+
+	for _, sql := range tests {
+		query, err := parser.parseSQL(sql)
+		if err != nil {
+			t.Fatalf("parseSQL(%q) error = %v", sql, err)
+		}
+		if got := query.String(); got != sql {
+			t.Errorf("String() = %q, expected %q", got, sql)
+		}
+	}
+}
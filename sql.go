@@ -2,167 +2,218 @@ package sheetsql
 
 import (
 	"fmt"
-	"regexp"
-	"strconv"
-	"strings"
 )
 
 type SQLParser struct {
 	client *Client
+
+	// functions holds the functions added with RegisterFunction, by
+	// upper-case name.
+	functions map[string]*scalarFunction
+
+	cache *stmtCache
 }
 
 func NewSQLParser(client *Client) *SQLParser {
-	return &SQLParser{client: client}
+	return &SQLParser{client: client, cache: newStmtCache(DefaultStatementCacheSize)}
 }
 
-func (p *SQLParser) Query(sql string, dest interface{}) error {
-	query, err := p.parseSQL(sql)
+// Query runs a SELECT statement and appends the rows to dest, a pointer to a
+// slice of structs. args are bound to the placeholders in sql: ? or $1, $2,
+// ... take one argument each, in order, and :name takes sql.Named("name", v)
+// arguments or a single map or struct holding the names:
+//
+//	err := parser.Query("SELECT * FROM Users WHERE City = ? AND Age > ?", &users, "Boston", 30)
+//	err = parser.Query("SELECT * FROM Users WHERE City = :city", &users, map[string]any{"city": "Boston"})
+//
+// Arguments are used as they are, never spliced into the SQL text.
+func (p *SQLParser) Query(sql string, dest interface{}, args ...interface{}) error {
+	sel, err := p.parseSelectStmt(sql)
 	if err != nil {
 		return fmt.Errorf("failed to parse SQL: %w", err)
 	}
 
-	return query.Get(dest)
+	exec, err := newBoundExecution(sel, args)
+	if err != nil {
+		return err
+	}
+	return sel.query.get(exec, dest)
 }
 
-func (p *SQLParser) parseSQL(sql string) (*Query, error) {
-	sql = strings.TrimSpace(sql)
-	sql = regexp.MustCompile(`\s+`).ReplaceAllString(sql, " ")
-
-	selectRegex := regexp.MustCompile(`(?i)^SELECT\s+(.+?)\s+FROM\s+(\w+)(?:\s+WHERE\s+(.+?))?(?:\s+LIMIT\s+(\d+))?(?:\s+OFFSET\s+(\d+))?$`)
-	matches := selectRegex.FindStringSubmatch(sql)
-
-	if len(matches) == 0 {
-		return nil, fmt.Errorf("invalid SQL syntax")
+// parse turns SQL text into a statement, or returns the statement cached for
+// it. Syntax errors are returned as *SyntaxError.
+func (p *SQLParser) parse(sql string) (statement, error) {
+	version := p.client.version()
+	if stmt, ok := p.cache.get(sql, version); ok {
+		return stmt, nil
 	}
 
-	tableName := matches[2]
-	query := p.client.From(tableName)
+	ps, err := p.newParser(sql)
+	if err != nil {
+		return nil, err
+	}
 
-	if matches[3] != "" {
-		whereClause := matches[3]
-		if err := p.parseWhere(query, whereClause); err != nil {
-			return nil, fmt.Errorf("failed to parse WHERE clause: %w", err)
-		}
+	stmt, err := ps.parseStatement()
+	if err != nil {
+		return nil, err
 	}
+	p.cache.put(sql, version, stmt)
+	return stmt, nil
+}
 
-	if matches[4] != "" {
-		limit, err := strconv.Atoi(matches[4])
-		if err != nil {
-			return nil, fmt.Errorf("invalid LIMIT value: %w", err)
-		}
-		query.Limit(limit)
+// newParser returns a parser for sql that knows p's registered functions.
+func (p *SQLParser) newParser(sql string) (*parser, error) {
+	ps, err := newParser(sql, p.client)
+	if err != nil {
+		return nil, err
 	}
+	ps.functions = p.functions
+	return ps, nil
+}
 
-	if matches[5] != "" {
-		offset, err := strconv.Atoi(matches[5])
-		if err != nil {
-			return nil, fmt.Errorf("invalid OFFSET value: %w", err)
-		}
-		query.Offset(offset)
+func (p *SQLParser) parseSQL(sql string) (*Query, error) {
+	sel, err := p.parseSelectStmt(sql)
+	if err != nil {
+		return nil, err
 	}
 
-	return query, nil
+	return sel.query, nil
 }
 
-func (p *SQLParser) parseWhere(query *Query, whereClause string) error {
-	conditions := regexp.MustCompile(`(?i)\s+AND\s+`).Split(whereClause, -1)
-
-	for _, condition := range conditions {
-		condition = strings.TrimSpace(condition)
+func (p *SQLParser) parseSelectStmt(sql string) (*selectStmt, error) {
+	stmt, err := p.parse(sql)
+	if err != nil {
+		return nil, err
+	}
 
-		operatorRegex := regexp.MustCompile(`(\w+)\s*(=|!=|<>|<=|>=|<|>|LIKE)\s*(.+)`)
-		matches := operatorRegex.FindStringSubmatch(condition)
+	sel, ok := stmt.(*selectStmt)
+	if !ok {
+		return nil, fmt.Errorf("expected a SELECT statement")
+	}
 
-		if len(matches) != 4 {
-			return fmt.Errorf("invalid WHERE condition: %s", condition)
-		}
+	return sel, nil
+}
 
-		column := matches[1]
-		operator := matches[2]
-		value := strings.Trim(matches[3], "'\"")
+// newBoundExecution starts an execution of stmt with args bound to its
+// placeholders.
+func newBoundExecution(stmt statement, args []interface{}) (*execution, error) {
+	b, err := stmt.statementParams().bind(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind arguments: %w", err)
+	}
 
-		if operator == "<>" {
-			operator = "!="
-		}
+	exec := newExecution(stmt.statementQuery().client)
+	exec.bindings = b
+	return exec, nil
+}
 
-		var parsedValue interface{}
-		if intVal, err := strconv.Atoi(value); err == nil {
-			parsedValue = intVal
-		} else if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
-			parsedValue = floatVal
-		} else if boolVal, err := strconv.ParseBool(value); err == nil {
-			parsedValue = boolVal
-		} else {
-			parsedValue = value
-		}
+func (p *SQLParser) parseWhere(query *Query, whereClause string) error {
+	ps, err := p.newParser(whereClause)
+	if err != nil {
+		return err
+	}
 
-		query.Where(column, operator, parsedValue)
+	if err := ps.parseWhere(query); err != nil {
+		return err
 	}
 
-	return nil
+	return ps.expectEOF()
 }
 
-func (p *SQLParser) Insert(sql string, data interface{}) error {
-	sql = strings.TrimSpace(sql)
-	sql = regexp.MustCompile(`\s+`).ReplaceAllString(sql, " ")
+// Insert runs an INSERT statement. Rows given with VALUES or copied with
+// SELECT are matched to the sheet's columns by name and appended in a single
+// request, with args bound to placeholders as in Query:
+//
+//	err := parser.Insert("INSERT INTO Users (Name, Age) VALUES (?, ?), ('Bob', 41)", "Ann", 35)
+//	err = parser.Insert("INSERT INTO Archive SELECT * FROM Orders WHERE Shipped < DATE '2024-01-01'")
+//
+// A statement naming only the sheet writes the row struct given as its
+// argument, as Query.Insert does.
+func (p *SQLParser) Insert(sql string, args ...interface{}) error {
+	ins, err := p.parseInsert(sql)
+	if err != nil {
+		return err
+	}
 
-	insertRegex := regexp.MustCompile(`(?i)^INSERT\s+INTO\s+(\w+)`)
-	matches := insertRegex.FindStringSubmatch(sql)
+	_, err = execStatement(ins, args)
+	return err
+}
 
-	if len(matches) == 0 {
-		return fmt.Errorf("invalid INSERT SQL syntax")
+func (p *SQLParser) parseInsert(sql string) (*insertStmt, error) {
+	stmt, err := p.parse(sql)
+	if err != nil {
+		return nil, fmt.Errorf("invalid INSERT SQL syntax: %w", err)
 	}
 
-	tableName := matches[1]
-	query := p.client.From(tableName)
+	ins, ok := stmt.(*insertStmt)
+	if !ok {
+		return nil, fmt.Errorf("expected an INSERT statement")
+	}
 
-	return query.Insert(data)
+	return ins, nil
 }
 
-func (p *SQLParser) Update(sql string, data interface{}) error {
-	sql = strings.TrimSpace(sql)
-	sql = regexp.MustCompile(`\s+`).ReplaceAllString(sql, " ")
-
-	updateRegex := regexp.MustCompile(`(?i)^UPDATE\s+(\w+)\s+SET\s+.+?(?:\s+WHERE\s+(.+?))?$`)
-	matches := updateRegex.FindStringSubmatch(sql)
+// Update runs an UPDATE statement. The SET assignments are evaluated for each
+// matching row and may refer to its columns:
+//
+//	err := parser.Update("UPDATE Users SET Age = Age + 1, City = ? WHERE ID = ?", "Boston", 7)
+//
+// args are bound to placeholders as in Query. A statement without
+// placeholders may instead be given a row struct, whose columns are written
+// as by Query.Update before the assignments are applied.
+func (p *SQLParser) Update(sql string, args ...interface{}) error {
+	upd, err := p.parseUpdate(sql)
+	if err != nil {
+		return err
+	}
 
-	if len(matches) == 0 {
-		return fmt.Errorf("invalid UPDATE SQL syntax")
+	data, args := splitRowStruct(upd, args)
+	exec, err := newBoundExecution(upd, args)
+	if err != nil {
+		return err
 	}
+	return upd.query.updateMatching(exec, data, upd.assignments)
+}
 
-	tableName := matches[1]
-	query := p.client.From(tableName)
+func (p *SQLParser) parseUpdate(sql string) (*updateStmt, error) {
+	stmt, err := p.parse(sql)
+	if err != nil {
+		return nil, fmt.Errorf("invalid UPDATE SQL syntax: %w", err)
+	}
 
-	if len(matches) > 2 && matches[2] != "" {
-		whereClause := matches[2]
-		if err := p.parseWhere(query, whereClause); err != nil {
-			return fmt.Errorf("failed to parse WHERE clause: %w", err)
-		}
+	upd, ok := stmt.(*updateStmt)
+	if !ok {
+		return nil, fmt.Errorf("expected an UPDATE statement")
 	}
 
-	return query.Update(data)
+	return upd, nil
 }
 
-func (p *SQLParser) Delete(sql string) error {
-	sql = strings.TrimSpace(sql)
-	sql = regexp.MustCompile(`\s+`).ReplaceAllString(sql, " ")
-
-	deleteRegex := regexp.MustCompile(`(?i)^DELETE\s+FROM\s+(\w+)(?:\s+WHERE\s+(.+?))?$`)
-	matches := deleteRegex.FindStringSubmatch(sql)
+// Delete runs a DELETE statement, binding args to its placeholders as Query
+// does.
+func (p *SQLParser) Delete(sql string, args ...interface{}) error {
+	del, err := p.parseDelete(sql)
+	if err != nil {
+		return err
+	}
 
-	if len(matches) == 0 {
-		return fmt.Errorf("invalid DELETE SQL syntax")
+	exec, err := newBoundExecution(del, args)
+	if err != nil {
+		return err
 	}
+	return del.query.deleteMatching(exec)
+}
 
-	tableName := matches[1]
-	query := p.client.From(tableName)
+func (p *SQLParser) parseDelete(sql string) (*deleteStmt, error) {
+	stmt, err := p.parse(sql)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DELETE SQL syntax: %w", err)
+	}
 
-	if len(matches) > 2 && matches[2] != "" {
-		whereClause := matches[2]
-		if err := p.parseWhere(query, whereClause); err != nil {
-			return fmt.Errorf("failed to parse WHERE clause: %w", err)
-		}
+	del, ok := stmt.(*deleteStmt)
+	if !ok {
+		return nil, fmt.Errorf("expected a DELETE statement")
 	}
 
-	return query.Delete()
+	return del, nil
 }
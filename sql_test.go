@@ -1,6 +1,8 @@
 package sheetsql
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -108,8 +110,8 @@ func TestSQLParser_parseSQL(t *testing.T) {
 				t.Errorf("parseSQL() table = %v, expected %v", query.sheetName, tt.expected.table)
 			}
 
-			if len(query.where) != tt.expected.where {
-				t.Errorf("parseSQL() where clauses = %v, expected %v", len(query.where), tt.expected.where)
+			if len(comparisons(query.where)) != tt.expected.where {
+				t.Errorf("parseSQL() where clauses = %v, expected %v", len(comparisons(query.where)), tt.expected.where)
 			}
 
 			if query.limit != tt.expected.limit {
@@ -195,7 +197,7 @@ func TestSQLParser_parseWhere(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			query.where = make([]WhereClause, 0)
+			query.where = nil
 			err := parser.parseWhere(query, tt.whereClause)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("parseWhere() error = %v, wantErr %v", err, tt.wantErr)
@@ -206,27 +208,362 @@ func TestSQLParser_parseWhere(t *testing.T) {
 				return
 			}
 
-			if len(query.where) != len(tt.expected) {
-				t.Errorf("parseWhere() clause count = %v, expected %v", len(query.where), len(tt.expected))
+			actualClauses := comparisons(query.where)
+			if len(actualClauses) != len(tt.expected) {
+				t.Errorf("parseWhere() clause count = %v, expected %v", len(actualClauses), len(tt.expected))
 				return
 			}
 
 			for i, expected := range tt.expected {
-				actual := query.where[i]
-				if actual.Column != expected.Column {
-					t.Errorf("parseWhere() clause %d column = %v, expected %v", i, actual.Column, expected.Column)
+				actual := actualClauses[i]
+				if column, ok := actual.left.(*columnRef); !ok || column.name != expected.Column {
+					t.Errorf("parseWhere() clause %d column = %v, expected %v", i, actual.left, expected.Column)
 				}
-				if actual.Operator != expected.Operator {
-					t.Errorf("parseWhere() clause %d operator = %v, expected %v", i, actual.Operator, expected.Operator)
+				if actual.op != expected.Operator {
+					t.Errorf("parseWhere() clause %d operator = %v, expected %v", i, actual.op, expected.Operator)
 				}
-				if actual.Value != expected.Value {
-					t.Errorf("parseWhere() clause %d value = %v, expected %v", i, actual.Value, expected.Value)
+				if value, ok := actual.right.(*literal); !ok || value.value != expected.Value {
+					t.Errorf("parseWhere() clause %d value = %v, expected %v", i, actual.right, expected.Value)
 				}
 			}
 		})
 	}
 }
 
+func TestSQLParser_parseWhere_BooleanExpressions(t *testing.T) {
+	client := &Client{}
+	parser := NewSQLParser(client)
+
+	headers := []string{"Status", "Age", "City"}
+	fieldMap := map[string]int{"Status": 0, "Age": 1, "City": 2}
+
+	tests := []struct {
+		name        string
+		whereClause string
+		expected    string
+		matches     []bool
+	}{
+		{
+			name:        "or",
+			whereClause: "status = 'open' OR status = 'pending'",
+			expected:    "status = 'open' OR status = 'pending'",
+		},
+		{
+			name:        "and binds tighter than or",
+			whereClause: "Status = 'open' OR Status = 'pending' AND Age > 30",
+			expected:    "Status = 'open' OR Status = 'pending' AND Age > 30",
+			matches:     []bool{true, false, true, false},
+		},
+		{
+			name:        "parentheses",
+			whereClause: "(Status = 'open' OR Status = 'pending') AND Age > 30",
+			expected:    "(Status = 'open' OR Status = 'pending') AND Age > 30",
+			matches:     []bool{false, false, true, false},
+		},
+		{
+			name:        "not",
+			whereClause: "NOT (City = 'Boston' OR City = 'Chicago') AND NOT Age < 18",
+			expected:    "NOT (City = 'Boston' OR City = 'Chicago') AND NOT Age < 18",
+			matches:     []bool{false, true, true, false},
+		},
+		{
+			name:        "missing cell is unknown",
+			whereClause: "NOT City = 'Boston'",
+			expected:    "NOT City = 'Boston'",
+			matches:     []bool{false, true, true, false},
+		},
+	}
+
+	rows := [][]interface{}{
+		{"open", "25", "Boston"},
+		{"pending", "25", "Denver"},
+		{"pending", "41", "Austin"},
+		{"closed", "17"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := client.From("TestSheet")
+			if err := parser.parseWhere(query, tt.whereClause); err != nil {
+				t.Fatalf("parseWhere() error = %v", err)
+			}
+
+			if query.where.String() != tt.expected {
+				t.Errorf("parseWhere() = %s, expected %s", query.where, tt.expected)
+			}
+
+			for i, expected := range tt.matches {
+				actual, err := query.matchesWhere(rows[i], headers, fieldMap)
+				if err != nil {
+					t.Fatalf("matchesWhere() error = %v", err)
+				}
+				if actual != expected {
+					t.Errorf("row %d matches = %v, expected %v", i, actual, expected)
+				}
+			}
+		})
+	}
+}
+
+// comparisons returns the comparisons of an AND-only WHERE expression in
+// order.
+func comparisons(e expr) []*comparison {
+	switch e := e.(type) {
+	case *comparison:
+		return []*comparison{e}
+	case *logicalExpr:
+		return append(comparisons(e.left), comparisons(e.right)...)
+	}
+	return nil
+}
+
+func TestSQLParser_Query_Projection(t *testing.T) {
+	parser := NewSQLParser(SetupTestData().Client("test-id"))
+
+	type Contact struct {
+		FullName string `sheet:"FullName"`
+		Email    string `sheet:"Email"`
+		City     string `sheet:"City"`
+	}
+
+	var contacts []Contact
+	err := parser.Query("SELECT Name AS FullName, Email FROM Users WHERE City = 'Boston'", &contacts)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	expected := []Contact{{FullName: "Charlie Wilson", Email: "charlie@example.com"}}
+	if !reflect.DeepEqual(contacts, expected) {
+		t.Errorf("Query() = %+v, expected %+v", contacts, expected)
+	}
+
+	tests := []struct {
+		name    string
+		sql     string
+		columns []string
+		wantErr string
+	}{
+		{name: "star", sql: "SELECT * FROM Users", columns: []string{"ID", "Name", "Email", "Age", "City"}},
+		{name: "columns", sql: "SELECT City, Name FROM Users", columns: []string{"City", "Name"}},
+		{name: "implicit alias", sql: "SELECT Name who FROM Users", columns: []string{"who"}},
+		{name: "quoted alias", sql: "SELECT Name AS `Full Name`, ID AS 'Key' FROM Users", columns: []string{"Full Name", "Key"}},
+		{name: "star and column", sql: "SELECT *, Name AS Again FROM Users", columns: []string{"ID", "Name", "Email", "Age", "City", "Again"}},
+		{name: "unknown column", sql: "SELECT Name, Phone FROM Users", wantErr: `unknown column "Phone" in select list`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := parser.parseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("parseSQL() error = %v", err)
+			}
+
+			columns, _, err := query.rows()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("rows() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rows() error = %v", err)
+			}
+			if !reflect.DeepEqual(columns, tt.columns) {
+				t.Errorf("columns = %v, expected %v", columns, tt.columns)
+			}
+		})
+	}
+}
+
+func TestSQLParser_Query_OrderBy(t *testing.T) {
+	parser := NewSQLParser(SetupTestData().Client("test-id"))
+
+	tests := []struct {
+		name     string
+		sql      string
+		expected []int
+	}{
+		{"ascending", "SELECT * FROM Users ORDER BY Age", []int{5, 2, 4, 1, 3}},
+		{"descending with limit", "SELECT * FROM Users ORDER BY Age DESC LIMIT 2", []int{3, 1}},
+		{"two keys", "SELECT * FROM Users ORDER BY City ASC, Name DESC", []int{5, 3, 2, 1, 4}},
+		{"where and offset", "SELECT * FROM Users WHERE Age > 24 ORDER BY Name OFFSET 1 LIMIT 2", []int{3, 2}},
+		{"alias", "SELECT ID, Age AS Years FROM Users ORDER BY Years DESC NULLS LAST", []int{3, 1, 4, 2, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var users []User
+			if err := parser.Query(tt.sql, &users); err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+
+			var ids []int
+			for _, user := range users {
+				ids = append(ids, user.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("Query() IDs = %v, expected %v", ids, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSQLParser_Query_Aggregates(t *testing.T) {
+	mock := NewMockSheetsService()
+	mock.AddSheetData("Orders", [][]interface{}{
+		{"ID", "Customer", "Region", "Amount"},
+		{"1", "ann", "north", "10"},
+		{"2", "ben", "south", "5.5"},
+		{"3", "ann", "north", "20"},
+		{"4", "cat", "south"},
+		{"5", "ann", "west", "4"},
+	})
+	parser := NewSQLParser(mock.Client("test-id"))
+
+	tests := []struct {
+		name     string
+		sql      string
+		columns  []string
+		expected [][]interface{}
+		wantErr  string
+	}{
+		{
+			name:     "count star",
+			sql:      "SELECT COUNT(*) FROM Orders",
+			columns:  []string{"COUNT(*)"},
+			expected: [][]interface{}{{5}},
+		},
+		{
+			name:     "count ignores empty cells",
+			sql:      "SELECT COUNT(Amount) AS n, COUNT(DISTINCT Customer) AS customers FROM Orders",
+			columns:  []string{"n", "customers"},
+			expected: [][]interface{}{{4, 3}},
+		},
+		{
+			name:     "count of no rows",
+			sql:      "SELECT COUNT(*) AS n, SUM(Amount) AS total FROM Orders WHERE Region = 'east'",
+			columns:  []string{"n", "total"},
+			expected: [][]interface{}{{0, nil}},
+		},
+		{
+			name:     "group by",
+			sql:      "SELECT Region, SUM(Amount) AS total, MIN(Amount) AS low, MAX(Amount) AS high FROM Orders GROUP BY Region",
+			columns:  []string{"Region", "total", "low", "high"},
+			expected: [][]interface{}{{"north", int64(30), "10", "20"}, {"south", 5.5, "5.5", "5.5"}, {"west", int64(4), "4", "4"}},
+		},
+		{
+			name:     "having and order by aggregate",
+			sql:      "SELECT Customer, COUNT(*) AS orders FROM Orders GROUP BY Customer HAVING COUNT(*) > 1 OR Customer = 'cat' ORDER BY orders DESC",
+			columns:  []string{"Customer", "orders"},
+			expected: [][]interface{}{{"ann", 3}, {"cat", 1}},
+		},
+		{
+			name:     "avg",
+			sql:      "SELECT AVG(Amount) AS mean FROM Orders WHERE Customer = 'ann'",
+			columns:  []string{"mean"},
+			expected: [][]interface{}{{float64(34) / 3}},
+		},
+		{
+			name:    "ungrouped column",
+			sql:     "SELECT Customer, COUNT(*) FROM Orders GROUP BY Region",
+			wantErr: `column "Customer" must appear in GROUP BY or be used in an aggregate function`,
+		},
+		{
+			name:    "star with group by",
+			sql:     "SELECT * FROM Orders GROUP BY Region",
+			wantErr: "SELECT * cannot be used with GROUP BY or aggregate functions",
+		},
+		{
+			name:    "aggregate in where",
+			sql:     "SELECT * FROM Orders WHERE COUNT(*) > 1",
+			wantErr: "aggregate functions are not allowed in WHERE",
+		},
+		{
+			name:    "not a number",
+			sql:     "SELECT SUM(Customer) FROM Orders",
+			wantErr: `SUM(Customer): "ann" is not a number`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := parser.parseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("parseSQL() error = %v", err)
+			}
+
+			columns, rows, err := query.rows()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("rows() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rows() error = %v", err)
+			}
+			if !reflect.DeepEqual(columns, tt.columns) {
+				t.Errorf("columns = %v, expected %v", columns, tt.columns)
+			}
+			if !reflect.DeepEqual(rows, tt.expected) {
+				t.Errorf("rows = %v, expected %v", rows, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSQLParser_Query_Operators(t *testing.T) {
+	mock := NewMockSheetsService()
+	mock.AddSheetData("Tasks", [][]interface{}{
+		{"Name", "Owner", "Points"},
+		{"Ann", "sam", "3"},
+		{"Ben", "", "5"},
+		{"Cat"},
+		{"Dan", "kim", "8"},
+	})
+	parser := NewSQLParser(mock.Client("test-id"))
+
+	tests := []struct {
+		name     string
+		where    string
+		expected []string
+	}{
+		{"in list", "Owner IN ('sam', 'kim')", []string{"Ann", "Dan"}},
+		{"not in list", "Owner NOT IN ('sam')", []string{"Ben", "Dan"}},
+		{"not in with null", "Owner NOT IN ('sam', NULL)", nil},
+		{"in with expressions", "Points IN (1, Points)", []string{"Ann", "Ben", "Dan"}},
+		{"between", "Points BETWEEN 3 AND 5", []string{"Ann", "Ben"}},
+		{"between binds before and", "Points BETWEEN 3 AND 5 AND Owner = 'sam'", []string{"Ann"}},
+		{"not between", "Points NOT BETWEEN 3 AND 5", []string{"Dan"}},
+		{"is null", "Owner IS NULL", []string{"Cat"}},
+		{"is not null", "Owner IS NOT NULL", []string{"Ann", "Ben", "Dan"}},
+		{"empty cell", "Owner = ''", []string{"Ben"}},
+		{"not like", "Name NOT LIKE '%a%'", []string{"Ann", "Ben"}},
+		{"negated in", "NOT Owner IN ('sam')", []string{"Ben", "Dan"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := parser.parseSQL("SELECT Name FROM Tasks WHERE " + tt.where)
+			if err != nil {
+				t.Fatalf("parseSQL() error = %v", err)
+			}
+
+			_, rows, err := query.rows()
+			if err != nil {
+				t.Fatalf("rows() error = %v", err)
+			}
+			var names []string
+			for _, row := range rows {
+				names = append(names, row[0].(string))
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("rows = %v, expected %v", names, tt.expected)
+			}
+		})
+	}
+}
+
 func TestSQLParser_Insert(t *testing.T) {
 	client := &Client{}
 	parser := NewSQLParser(client)
@@ -272,6 +609,104 @@ func TestSQLParser_Insert(t *testing.T) {
 	}
 }
 
+func TestSQLParser_Insert_Rows(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		args     []interface{}
+		sheet    string
+		expected [][]interface{}
+		wantErr  string
+	}{
+		{
+			name:     "values with columns",
+			sql:      "INSERT INTO Users (Name, ID) VALUES ('Dan', 4), (?, 2 + 3)",
+			args:     []interface{}{"Eve"},
+			sheet:    "Users",
+			expected: [][]interface{}{{4, "Dan", nil}, {int64(5), "Eve", nil}},
+		},
+		{
+			name:     "values in sheet order",
+			sql:      "INSERT INTO Users VALUES (4, NULL, 30), (5, 'Eve')",
+			sheet:    "Users",
+			expected: [][]interface{}{{4, "", 30}, {5, "Eve", nil}},
+		},
+		{
+			name:     "select by column name",
+			sql:      "INSERT INTO Archive SELECT Total, ID FROM Orders WHERE UserID = 1",
+			sheet:    "Archive",
+			expected: [][]interface{}{{"100", "5", nil}, {"102", "9", nil}},
+		},
+		{
+			name:     "select into columns",
+			sql:      "INSERT INTO Archive (ID, Note) SELECT ID, 'from ' || Name FROM Users WHERE TeamID IS NOT NULL",
+			sheet:    "Archive",
+			expected: [][]interface{}{{"1", nil, "from Ann"}, {"2", nil, "from Ben"}},
+		},
+		{
+			name:     "select from the same sheet",
+			sql:      "INSERT INTO Users SELECT ID + 10 AS ID, Name FROM Users WHERE ID = ?",
+			args:     []interface{}{3},
+			sheet:    "Users",
+			expected: [][]interface{}{{int64(13), "Cat", nil}},
+		},
+		{
+			name:    "unknown column",
+			sql:     "INSERT INTO Archive SELECT * FROM Orders",
+			wantErr: `unknown column "UserID" in INSERT`,
+		},
+		{
+			name:    "too many values",
+			sql:     "INSERT INTO Users VALUES (4, 'Dan', 10, 'x')",
+			wantErr: "VALUES has 4 values for 3 columns",
+		},
+		{
+			name:    "select column count",
+			sql:     "INSERT INTO Archive (ID) SELECT ID, Total FROM Orders",
+			wantErr: "INSERT has 1 columns but SELECT returns 2",
+		},
+		{
+			name:    "column in values",
+			sql:     "INSERT INTO Users (ID) VALUES (Name)",
+			wantErr: `unknown column "Name"`,
+		},
+		{
+			name:    "values column count",
+			sql:     "INSERT INTO Users (ID, Name) VALUES (4, 'Dan'), (5)",
+			wantErr: "invalid INSERT SQL syntax: line 1, column 49: VALUES has 1 values for 2 columns",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newJoinTestBackend()
+			backend.SetSheet("Archive", [][]interface{}{{"ID", "Total", "Note"}})
+			before := len(backend.Sheet(tt.sheet))
+
+			err := NewSQLParser(NewClientWithBackend("test-id", backend)).Insert(tt.sql, tt.args...)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Insert() error = %v, expected %q", err, tt.wantErr)
+				}
+				if backend.appends != 0 {
+					t.Errorf("Insert() appended rows despite the error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Insert() error = %v", err)
+			}
+
+			if got := backend.Sheet(tt.sheet)[before:]; !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("appended rows = %v, expected %v", got, tt.expected)
+			}
+			if backend.reads != 1 || backend.appends != 1 {
+				t.Errorf("Insert() made %d read and %d append requests, expected 1 each", backend.reads, backend.appends)
+			}
+		})
+	}
+}
+
 func TestSQLParser_Update(t *testing.T) {
 	client := &Client{}
 	parser := NewSQLParser(client)
@@ -322,6 +757,97 @@ func TestSQLParser_Update(t *testing.T) {
 	}
 }
 
+func TestSQLParser_Update_Set(t *testing.T) {
+	type cityOnly struct {
+		City string `sheet:"City"`
+	}
+
+	tests := []struct {
+		name     string
+		sql      string
+		args     []interface{}
+		expected []string
+		wantErr  string
+	}{
+		{
+			name:     "expressions",
+			sql:      "UPDATE Users SET Age = Age + 1, City = 'Boston' WHERE ID < 3",
+			expected: []string{"[1 John Doe john@example.com 31 Boston]", "[2 Jane Smith jane@example.com 26 Boston]"},
+		},
+		{
+			name:     "assignments see the old row",
+			sql:      "UPDATE Users SET Name = City, City = Name WHERE ID = 1",
+			expected: []string{"[1 New York john@example.com 30 John Doe]"},
+		},
+		{
+			name:     "placeholders",
+			sql:      "UPDATE Users SET City = UPPER(?) WHERE Age > ?",
+			args:     []interface{}{"Denver", 29},
+			expected: []string{"[1 John Doe john@example.com 30 DENVER]", "[3 Bob Johnson bob@example.com 35 DENVER]"},
+		},
+		{
+			name:     "null empties the cell",
+			sql:      "UPDATE Users SET Email = NULL WHERE ID = 5",
+			expected: []string{"[5 Charlie Wilson  22 Boston]"},
+		},
+		{
+			name:     "struct then SET",
+			sql:      "UPDATE Users SET Age = Age * 2 WHERE ID = 4",
+			args:     []interface{}{&cityOnly{City: "Austin"}},
+			expected: []string{"[4 Alice Brown alice@example.com 56 Austin]"},
+		},
+		{
+			name:    "unknown column",
+			sql:     "UPDATE Users SET Country = 'US'",
+			wantErr: `unknown column "Country" in SET`,
+		},
+		{
+			name:    "failing expression",
+			sql:     "UPDATE Users SET Age = CAST(Name AS INT) WHERE ID = 1",
+			wantErr: `SET Age: cannot cast "John Doe" to INT`,
+		},
+		{
+			name:    "no match",
+			sql:     "UPDATE Users SET Age = 0 WHERE City = 'Paris'",
+			wantErr: "no rows matched the where conditions",
+		},
+		{
+			name:    "arguments without placeholders",
+			sql:     "UPDATE Users SET Age = 0",
+			args:    []interface{}{1, 2},
+			wantErr: "failed to bind arguments: expected 0 arguments, got 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := SetupTestData()
+			parser := NewSQLParser(mock.Client("test-id"))
+
+			err := parser.Update(tt.sql, tt.args...)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Update() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+
+			var changed []string
+			for i, row := range mock.GetSheetData("Users")[1:] {
+				if after := fmt.Sprint(row); after != fmt.Sprint(SetupTestData().GetSheetData("Users")[i+1]) {
+					changed = append(changed, after)
+				}
+			}
+			if !reflect.DeepEqual(changed, tt.expected) {
+				t.Errorf("changed rows = %q, expected %q", changed, tt.expected)
+			}
+		})
+	}
+}
+
 func TestSQLParser_Delete(t *testing.T) {
 	client := &Client{}
 	parser := NewSQLParser(client)
@@ -0,0 +1,203 @@
+package sheetsql
+
+import (
+	"container/list"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// DefaultStatementCacheSize is the number of parsed statements a new
+// SQLParser keeps.
+const DefaultStatementCacheSize = 256
+
+// Stmt is a prepared statement: SQL that has been parsed once and can be run
+// any number of times with different arguments. A Stmt is safe for use by
+// several goroutines at once.
+type Stmt struct {
+	stmt statement
+}
+
+// Prepare parses sql for running later with Stmt.Query or Stmt.Exec.
+// Placeholders are bound each time the statement runs:
+//
+//	stmt, err := parser.Prepare("SELECT * FROM Users WHERE City = ?")
+//	...
+//	err = stmt.Query(&users, "Boston")
+func (p *SQLParser) Prepare(sql string) (*Stmt, error) {
+	stmt, err := p.parse(sql)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SQL: %w", err)
+	}
+	return &Stmt{stmt: stmt}, nil
+}
+
+// Query runs a prepared SELECT statement and appends the rows to dest, a
+// pointer to a slice of structs, binding args as SQLParser.Query does.
+func (s *Stmt) Query(dest interface{}, args ...interface{}) error {
+	sel, ok := s.stmt.(*selectStmt)
+	if !ok {
+		return fmt.Errorf("expected a SELECT statement")
+	}
+
+	exec, err := newBoundExecution(sel, args)
+	if err != nil {
+		return err
+	}
+	return sel.query.get(exec, dest)
+}
+
+// Exec runs a prepared INSERT, UPDATE or DELETE statement and returns the
+// number of rows it changed. Args are bound to placeholders, except that an
+// INSERT without VALUES or SELECT takes the row struct as its only argument
+// and an UPDATE without placeholders may be given one as SQLParser.Update
+// can.
+func (s *Stmt) Exec(args ...interface{}) (int, error) {
+	return execStatement(s.stmt, args)
+}
+
+// execStatement runs a statement other than SELECT and returns the number of
+// rows it changed.
+func execStatement(stmt statement, args []interface{}) (int, error) {
+	switch stmt := stmt.(type) {
+	case *insertStmt:
+		if stmt.values != nil || stmt.source != nil {
+			exec, err := newBoundExecution(stmt, args)
+			if err != nil {
+				return 0, err
+			}
+			return stmt.query.insertRows(exec, stmt.columns, stmt.values, stmt.source)
+		}
+		data, err := rowArg(args)
+		if err != nil {
+			return 0, err
+		}
+		if err := stmt.query.Insert(data); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	case *updateStmt:
+		data, args := splitRowStruct(stmt, args)
+		exec, err := newBoundExecution(stmt, args)
+		if err != nil {
+			return 0, err
+		}
+		return stmt.query.update(exec, data, stmt.assignments)
+	case *deleteStmt:
+		exec, err := newBoundExecution(stmt, args)
+		if err != nil {
+			return 0, err
+		}
+		return stmt.query.delete(exec)
+	}
+	return 0, fmt.Errorf("cannot Exec a SELECT statement; use Query")
+}
+
+// rowArg returns the struct argument an INSERT writes to the sheet.
+func rowArg(args []interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected a single struct argument, got %d arguments", len(args))
+	}
+	return args[0], nil
+}
+
+// splitRowStruct returns the row struct of an UPDATE statement that has no
+// placeholders and was given a single struct, and the arguments left to bind.
+func splitRowStruct(stmt *updateStmt, args []interface{}) (interface{}, []interface{}) {
+	if len(args) != 1 || stmt.count > 0 || len(stmt.names) > 0 {
+		return nil, args
+	}
+
+	v := reflect.ValueOf(args[0])
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if _, isTime := args[0].(time.Time); v.Kind() != reflect.Struct || isTime {
+		return nil, args
+	}
+	return args[0], nil
+}
+
+// SetStatementCacheSize sets how many parsed statements p keeps, so that
+// running the same SQL again skips parsing. Least recently used statements
+// are dropped first; 0 turns the cache off.
+func (p *SQLParser) SetStatementCacheSize(n int) {
+	p.cache.resize(n)
+}
+
+// stmtCache is a least-recently-used cache of parsed statements keyed by
+// their SQL text.
+type stmtCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   list.List // most recently used first
+}
+
+type cacheEntry struct {
+	sql     string
+	version int // the client's operatorsVersion when sql was parsed
+	stmt    statement
+}
+
+func newStmtCache(size int) *stmtCache {
+	return &stmtCache{size: size, entries: make(map[string]*list.Element)}
+}
+
+// get returns the statement cached for sql, unless it was parsed before the
+// client's operators reached version.
+func (c *stmtCache) get(sql string, version int) (statement, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[sql]
+	if !ok || elem.Value.(*cacheEntry).version != version {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).stmt, true
+}
+
+func (c *stmtCache) put(sql string, version int, stmt statement) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[sql]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.version, entry.stmt = version, stmt
+		c.order.MoveToFront(elem)
+		return
+	}
+	if c.size <= 0 {
+		return
+	}
+	c.entries[sql] = c.order.PushFront(&cacheEntry{sql: sql, version: version, stmt: stmt})
+	c.evict()
+}
+
+func (c *stmtCache) resize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.size = size
+	c.evict()
+}
+
+// clear drops every statement, for when they may no longer parse the same.
+func (c *stmtCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.entries)
+	c.order.Init()
+}
+
+// evict drops the least recently used statements beyond the size.
+func (c *stmtCache) evict() {
+	for c.order.Len() > max(c.size, 0) {
+		elem := c.order.Back()
+		c.order.Remove(elem)
+		delete(c.entries, elem.Value.(*cacheEntry).sql)
+	}
+}
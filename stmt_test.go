@@ -0,0 +1,191 @@
+package sheetsql
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSQLParser_Prepare(t *testing.T) {
+	mock := SetupTestData()
+	parser := NewSQLParser(mock.Client("test-id"))
+
+	stmt, err := parser.Prepare("SELECT * FROM Users WHERE City = ? ORDER BY Age")
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	for city, expected := range map[string][]int{"New York": {4, 1}, "Boston": {5}, "Paris": nil} {
+		var users []User
+		if err := stmt.Query(&users, city); err != nil {
+			t.Fatalf("Query(%q) error = %v", city, err)
+		}
+		var ids []int
+		for _, user := range users {
+			ids = append(ids, user.ID)
+		}
+		if !reflect.DeepEqual(ids, expected) {
+			t.Errorf("Query(%q) IDs = %v, expected %v", city, ids, expected)
+		}
+	}
+
+	var users []User
+	if err := stmt.Query(&users); err == nil || err.Error() != "failed to bind arguments: expected 1 arguments, got 0" {
+		t.Errorf("Query() error = %v, expected a binding error", err)
+	}
+	if _, err := stmt.Exec("Boston"); err == nil || err.Error() != "cannot Exec a SELECT statement; use Query" {
+		t.Errorf("Exec() error = %v, expected SELECT to be rejected", err)
+	}
+
+	del, err := parser.Prepare("DELETE FROM Users WHERE Age < ?")
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	for _, tt := range []struct {
+		age      int
+		expected int
+	}{{23, 1}, {23, 0}, {29, 2}} {
+		n, err := del.Exec(tt.age)
+		if err != nil {
+			t.Fatalf("Exec(%d) error = %v", tt.age, err)
+		}
+		if n != tt.expected {
+			t.Errorf("Exec(%d) = %d, expected %d", tt.age, n, tt.expected)
+		}
+	}
+	if err := del.Query(&users, 30); err == nil || err.Error() != "expected a SELECT statement" {
+		t.Errorf("Query() error = %v, expected a SELECT error", err)
+	}
+
+	_, err = parser.Prepare("SELECT * FROM")
+	if err == nil || err.Error() != "failed to parse SQL: line 1, column 14: expected sheet name, found end of input" {
+		t.Errorf("Prepare() error = %v, expected a syntax error", err)
+	}
+}
+
+func TestStmt_Concurrent(t *testing.T) {
+	parser := NewSQLParser(SetupTestData().Client("test-id"))
+	stmt, err := parser.Prepare(`
+		SELECT Name FROM Users
+		WHERE Name LIKE ? AND Age >= (SELECT MIN(Age) FROM Users WHERE City = ?)
+		ORDER BY Name`)
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	type name struct {
+		Name string `sheet:"Name"`
+	}
+	cases := []struct {
+		pattern, city string
+		expected      []name
+	}{
+		{"J%", "Boston", []name{{"Jane Smith"}, {"John Doe"}}},
+		{"%o%", "Chicago", []name{{"Bob Johnson"}}},
+		{"A%", "New York", []name{{"Alice Brown"}}},
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				c := cases[(g+i)%len(cases)]
+				var names []name
+				if err := stmt.Query(&names, c.pattern, c.city); err != nil {
+					errs <- err
+					return
+				}
+				if !reflect.DeepEqual(names, c.expected) {
+					errs <- fmt.Errorf("Query(%q, %q) = %v, expected %v", c.pattern, c.city, names, c.expected)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestStmt_PatternCache(t *testing.T) {
+	stmt, err := NewSQLParser(SetupTestData().Client("test-id")).Prepare("SELECT * FROM Users WHERE Name LIKE ?")
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	patterns := &stmt.stmt.(*selectStmt).query.patterns
+
+	var users []User
+	if err := stmt.Query(&users, "J%"); err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	cached := patterns.entries["LIKE\x00\\\x00J%"]
+	if err := stmt.Query(&users, "J%"); err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if cached == nil || patterns.entries["LIKE\x00\\\x00J%"] != cached {
+		t.Error("Query() did not reuse the pattern compiled by an earlier run")
+	}
+
+	// A prepared statement lives on, so the patterns bound to it must not
+	// pile up.
+	for i := 0; i < 500; i++ {
+		if err := stmt.Query(&users, fmt.Sprintf("%%%d%%", i)); err != nil {
+			t.Fatalf("Query() error = %v", err)
+		}
+	}
+	if len(patterns.entries) > maxCachedPatterns {
+		t.Errorf("Query() kept %d compiled patterns, expected at most %d", len(patterns.entries), maxCachedPatterns)
+	}
+}
+
+func TestSQLParser_StatementCache(t *testing.T) {
+	parser := NewSQLParser(&Client{})
+	parse := func(sql string) statement {
+		t.Helper()
+		stmt, err := parser.parse(sql)
+		if err != nil {
+			t.Fatalf("parse(%q) error = %v", sql, err)
+		}
+		return stmt
+	}
+
+	a := parse("SELECT * FROM A")
+	if parse("SELECT * FROM A") != a {
+		t.Error("parse() did not reuse the cached statement")
+	}
+
+	parser.SetStatementCacheSize(2)
+	b := parse("SELECT * FROM B")
+	parse("SELECT * FROM A")
+	parse("SELECT * FROM C")
+	if parse("SELECT * FROM A") != a {
+		t.Error("parse() evicted the recently used statement")
+	}
+	if parse("SELECT * FROM B") == b {
+		t.Error("parse() kept the least recently used statement")
+	}
+
+	parser.RegisterFunction("NOOP", func(args ...Value) (Value, error) { return nil, nil })
+	if parse("SELECT * FROM A") == a {
+		t.Error("RegisterFunction() did not clear the cache")
+	}
+
+	// A registered operator is a keyword, so "near" is no longer an alias.
+	parse("SELECT Name near FROM Users")
+	parser.client.RegisterOperator("NEAR", func(left, right Value) (bool, error) { return false, nil })
+	if _, err := parser.parse("SELECT Name near FROM Users"); err == nil {
+		t.Error("parse() used a statement cached before RegisterOperator")
+	}
+
+	parser.SetStatementCacheSize(0)
+	if c := parse("SELECT * FROM C"); parse("SELECT * FROM C") == c {
+		t.Error("parse() cached a statement with the cache turned off")
+	}
+}
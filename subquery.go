@@ -0,0 +1,225 @@
+package sheetsql
+
+import (
+	"fmt"
+)
+
+// outerColumn marks a field map entry that belongs to the query enclosing a
+// subquery; such columns are evaluated against the outer row.
+const outerColumn = -2
+
+// execution holds the state shared by a query and its subqueries while they
+// run: the sheets read so far, the rows of common table expressions, the
+// results of subqueries that do not depend on the outer row and the values
+// bound to placeholders.
+type execution struct {
+	client     *Client
+	sheets     map[string][][]interface{}
+	ctes       map[*cte][][]interface{}
+	results    map[*Query]*subqueryResult
+	correlated map[*Query]bool
+	bindings   *bindings
+}
+
+type subqueryResult struct {
+	columns []string
+	rows    [][]interface{}
+}
+
+func newExecution(client *Client) *execution {
+	return &execution{
+		client:     client,
+		sheets:     make(map[string][][]interface{}),
+		ctes:       make(map[*cte][][]interface{}),
+		results:    make(map[*Query]*subqueryResult),
+		correlated: make(map[*Query]bool),
+	}
+}
+
+// read returns the values of readRanges, fetching the ones not read yet in a
+// single request.
+func (x *execution) read(readRanges []string) ([][][]interface{}, error) {
+	var missing []string
+	for _, r := range readRanges {
+		if _, ok := x.sheets[r]; !ok {
+			missing = append(missing, r)
+			x.sheets[r] = nil
+		}
+	}
+
+	switch len(missing) {
+	case 0:
+	case 1:
+		values, err := x.client.backend.ReadRange(x.client.spreadsheetID, missing[0])
+		if err != nil {
+			delete(x.sheets, missing[0])
+			return nil, fmt.Errorf("failed to read sheet: %w", err)
+		}
+		x.sheets[missing[0]] = values
+	default:
+		values, err := x.client.backend.ReadRanges(x.client.spreadsheetID, missing)
+		if err != nil {
+			for _, r := range missing {
+				delete(x.sheets, r)
+			}
+			return nil, fmt.Errorf("failed to read sheets: %w", err)
+		}
+		for i, r := range missing {
+			if i < len(values) {
+				x.sheets[r] = values[i]
+			}
+		}
+	}
+
+	values := make([][][]interface{}, len(readRanges))
+	for i, r := range readRanges {
+		values[i] = x.sheets[r]
+	}
+	return values, nil
+}
+
+// subquery runs q for the outer row env. Results are reused for later rows
+// unless q read a column of the outer row.
+func (x *execution) subquery(q *Query, env *evalEnv) (*subqueryResult, error) {
+	if result, ok := x.results[q]; ok {
+		return result, nil
+	}
+
+	columns, rows, err := q.run(x, env)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &subqueryResult{columns: columns, rows: rows}
+	if !x.correlated[q] {
+		x.results[q] = result
+	}
+	return result, nil
+}
+
+// runSubquery runs q for env, starting an execution if env is not part of
+// one.
+func runSubquery(q *Query, env *evalEnv) (*subqueryResult, error) {
+	if env.exec == nil {
+		env.exec = newExecution(q.client)
+	}
+	return env.exec.subquery(q, env)
+}
+
+// sheetRanges returns every range read by q, its subqueries, the queries it
+// is combined with and the common table expressions it uses, so that they can
+// all be fetched at once.
+func (q *Query) sheetRanges() []string {
+	var ranges []string
+	seen := make(map[string]bool)
+	seenCTEs := make(map[*cte]bool)
+
+	var visit func(q *Query)
+	visit = func(q *Query) {
+		for _, r := range q.tableRanges() {
+			if !seen[r] {
+				seen[r] = true
+				ranges = append(ranges, r)
+			}
+		}
+		for _, c := range q.tableCTEs() {
+			if c != nil && !seenCTEs[c] {
+				seenCTEs[c] = true
+				visit(c.query)
+				for _, op := range c.recursive {
+					visit(op.query)
+				}
+			}
+		}
+		for _, sub := range q.subqueries() {
+			visit(sub)
+		}
+		for _, op := range q.setOps {
+			visit(op.query)
+		}
+	}
+	visit(q)
+
+	return ranges
+}
+
+// subqueries returns the queries nested directly in q's expressions.
+func (q *Query) subqueries() []*Query {
+	exprs := []expr{q.where, q.having}
+	for _, item := range q.columns {
+		exprs = append(exprs, item.expr)
+	}
+	for _, item := range q.orderBy {
+		exprs = append(exprs, item.expr)
+	}
+	for _, j := range q.joins {
+		exprs = append(exprs, j.on)
+	}
+	return exprSubqueries(exprs)
+}
+
+// exprSubqueries returns the queries nested directly in exprs.
+func exprSubqueries(exprs []expr) []*Query {
+	var queries []*Query
+	for _, e := range exprs {
+		walkExpr(e, func(e expr) bool {
+			switch e := e.(type) {
+			case *subqueryExpr:
+				queries = append(queries, e.query)
+			case *existsExpr:
+				queries = append(queries, e.query)
+			case *inExpr:
+				if e.query != nil {
+					queries = append(queries, e.query)
+				}
+			}
+			return true
+		})
+	}
+	return queries
+}
+
+// subqueryExpr is a scalar subquery: a SELECT returning one column and at
+// most one row, used as a value.
+type subqueryExpr struct {
+	query *Query
+}
+
+func (s *subqueryExpr) eval(env *evalEnv) (interface{}, error) {
+	result, err := runSubquery(s.query, env)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.columns) != 1 {
+		return nil, fmt.Errorf("subquery must return one column, not %d", len(result.columns))
+	}
+
+	switch len(result.rows) {
+	case 0:
+		return nil, nil
+	case 1:
+		return result.rows[0][0], nil
+	}
+	return nil, fmt.Errorf("subquery returned more than one row")
+}
+
+func (s *subqueryExpr) String() string {
+	return "(" + s.query.String() + ")"
+}
+
+// existsExpr is true when its subquery returns any rows.
+type existsExpr struct {
+	query *Query
+}
+
+func (e *existsExpr) eval(env *evalEnv) (interface{}, error) {
+	result, err := runSubquery(e.query, env)
+	if err != nil {
+		return nil, err
+	}
+	return len(result.rows) > 0, nil
+}
+
+func (e *existsExpr) String() string {
+	return "EXISTS (" + e.query.String() + ")"
+}
@@ -0,0 +1,130 @@
+package sheetsql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuery_Subqueries(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected [][]interface{}
+		wantErr  string
+	}{
+		{
+			name:     "in subquery",
+			sql:      "SELECT ID FROM Orders WHERE UserID IN (SELECT ID FROM Users WHERE Name = 'Ann')",
+			expected: [][]interface{}{{"100"}, {"102"}},
+		},
+		{
+			name:     "not in subquery with NULL is unknown",
+			sql:      "SELECT ID FROM Orders WHERE NOT UserID IN (SELECT TeamID FROM Users)",
+			expected: nil,
+		},
+		{
+			name:     "correlated exists",
+			sql:      "SELECT Name FROM Users WHERE EXISTS (SELECT ID FROM Orders WHERE Orders.UserID = Users.ID AND Total > 6)",
+			expected: [][]interface{}{{"Ann"}, {"Ben"}},
+		},
+		{
+			name:     "not exists",
+			sql:      "SELECT Name FROM Users u WHERE NOT EXISTS (SELECT ID FROM Orders WHERE UserID = u.ID)",
+			expected: [][]interface{}{{"Cat"}},
+		},
+		{
+			name:     "scalar subquery in select list",
+			sql:      "SELECT Name, (SELECT COUNT(*) FROM Orders WHERE UserID = Users.ID) AS Orders FROM Users",
+			expected: [][]interface{}{{"Ann", 2}, {"Ben", 1}, {"Cat", 0}},
+		},
+		{
+			name:     "uncorrelated scalar subquery",
+			sql:      "SELECT ID FROM Orders WHERE Total = (SELECT MAX(Total) FROM Orders)",
+			expected: [][]interface{}{{"102"}},
+		},
+		{
+			name:     "nested subqueries",
+			sql:      "SELECT Team FROM Teams WHERE TeamID IN (SELECT TeamID FROM Users WHERE ID IN (SELECT UserID FROM Orders WHERE Total < 6))",
+			expected: [][]interface{}{{"red"}},
+		},
+		{
+			name:    "more than one row",
+			sql:     "SELECT Name FROM Users WHERE ID = (SELECT UserID FROM Orders)",
+			wantErr: "subquery returned more than one row",
+		},
+		{
+			name:    "more than one column",
+			sql:     "SELECT Name FROM Users WHERE ID IN (SELECT * FROM Orders)",
+			wantErr: "subquery must return one column, not 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newJoinTestBackend()
+			query, err := NewSQLParser(NewClientWithBackend("test-id", backend)).parseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("parseSQL() error = %v", err)
+			}
+
+			_, rows, err := query.rows()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("rows() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rows() error = %v", err)
+			}
+			if !reflect.DeepEqual(rows, tt.expected) {
+				t.Errorf("rows = %v, expected %v", rows, tt.expected)
+			}
+			if backend.reads != 1 {
+				t.Errorf("query made %d read requests, expected 1", backend.reads)
+			}
+		})
+	}
+}
+
+func TestSQLParser_Delete_Subquery(t *testing.T) {
+	backend := newJoinTestBackend()
+	parser := NewSQLParser(NewClientWithBackend("test-id", backend))
+
+	if err := parser.Delete("DELETE FROM Orders WHERE UserID IN (SELECT ID FROM Users WHERE Name = 'Ann')"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	expected := [][]interface{}{
+		{"ID", "UserID", "Total"},
+		{"101", "2", "7"},
+		{"103", "4", "1"},
+	}
+	if got := backend.Sheet("Orders"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Orders = %v, expected %v", got, expected)
+	}
+}
+
+func TestQuery_Subqueries_Fluent(t *testing.T) {
+	client := NewClientWithBackend("test-id", newJoinTestBackend())
+
+	// Name is a column of Users, so the subquery depends on the outer row.
+	fluent := client.From("Users").Where("TeamID", "IN", client.From("Teams").Where("Name", "=", "Ann").Select("TeamID"))
+	_, got, err := fluent.rows()
+	if err != nil {
+		t.Fatalf("rows() error = %v", err)
+	}
+
+	query, err := NewSQLParser(client).parseSQL("SELECT * FROM Users WHERE TeamID IN (SELECT TeamID FROM Teams WHERE Name = 'Ann')")
+	if err != nil {
+		t.Fatalf("parseSQL() error = %v", err)
+	}
+	_, expected, err := query.rows()
+	if err != nil {
+		t.Fatalf("rows() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got, expected) || len(expected) != 1 {
+		t.Errorf("fluent rows = %v, SQL rows = %v", got, expected)
+	}
+}
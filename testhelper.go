@@ -9,31 +9,30 @@ import (
 )
 
 type MockSheetsService struct {
-	mockData map[string][][]interface{}
+	*MemoryBackend
 }
 
 func NewMockSheetsService() *MockSheetsService {
 	return &MockSheetsService{
-		mockData: make(map[string][][]interface{}),
+		MemoryBackend: NewMemoryBackend(),
 	}
 }
 
 func (m *MockSheetsService) AddSheetData(sheetName string, data [][]interface{}) {
-	m.mockData[sheetName] = data
+	m.SetSheet(sheetName, data)
 }
 
 func (m *MockSheetsService) GetSheetData(sheetName string) [][]interface{} {
-	if data, exists := m.mockData[sheetName]; exists {
-		return data
-	}
-	return nil
+	return m.Sheet(sheetName)
+}
+
+// Client returns a Client that reads from and writes to the mock's sheets.
+func (m *MockSheetsService) Client(spreadsheetID string) *Client {
+	return NewClientWithBackend(spreadsheetID, m.MemoryBackend)
 }
 
 func NewMockClient(spreadsheetID string) *Client {
-	return &Client{
-		service:       nil,
-		spreadsheetID: spreadsheetID,
-	}
+	return NewMockSheetsService().Client(spreadsheetID)
 }
 
 func SetupTestData() *MockSheetsService {
@@ -76,8 +75,8 @@ func TestClientCreation(t *testing.T) {
 		t.Errorf("Expected spreadsheet ID 'test-id', got %s", client.spreadsheetID)
 	}
 
-	if client.service == nil {
-		t.Error("Expected service to be initialized")
+	if client.backend == nil {
+		t.Error("Expected backend to be initialized")
 	}
 }
 
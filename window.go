@@ -0,0 +1,296 @@
+package sheetsql
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// windowFunctions are the functions that exist only with OVER. Aggregate
+// functions can be used with OVER as well.
+var windowFunctions = map[string]*scalarFunction{
+	"ROW_NUMBER": {minArgs: 0, maxArgs: 0},
+	"RANK":       {minArgs: 0, maxArgs: 0},
+	"DENSE_RANK": {minArgs: 0, maxArgs: 0},
+	"LAG":        {minArgs: 1, maxArgs: 3},
+	"LEAD":       {minArgs: 1, maxArgs: 3},
+}
+
+// unboundedFrame is the offset of an UNBOUNDED PRECEDING or FOLLOWING frame
+// bound.
+const unboundedFrame = math.MaxInt32
+
+// windowExpr is a window function call, such as
+// SUM(Amount) OVER (PARTITION BY Account ORDER BY Date). Its values are
+// computed for all rows at once, after grouping, and looked up by eval.
+type windowExpr struct {
+	fn   string
+	args []expr
+	agg  *aggregateExpr // set for an aggregate used as a window function
+
+	partitionBy []expr
+	orderBy     []orderItem
+	frame       *windowFrame // nil for the default frame
+}
+
+// windowFrame is a ROWS frame: the rows from start to end relative to the
+// current row, where negative offsets precede it.
+type windowFrame struct {
+	start, end int
+}
+
+func (w *windowExpr) eval(env *evalEnv) (interface{}, error) {
+	value, ok := env.windows[w]
+	if !ok {
+		return nil, fmt.Errorf("window function %s is not allowed here", w.fn)
+	}
+	return value, nil
+}
+
+func (w *windowExpr) String() string {
+	var b strings.Builder
+	if w.agg != nil {
+		b.WriteString(w.agg.String())
+	} else {
+		args := make([]string, len(w.args))
+		for i, arg := range w.args {
+			args[i] = arg.String()
+		}
+		fmt.Fprintf(&b, "%s(%s)", w.fn, strings.Join(args, ", "))
+	}
+
+	var clauses []string
+	if len(w.partitionBy) > 0 {
+		exprs := make([]string, len(w.partitionBy))
+		for i, e := range w.partitionBy {
+			exprs[i] = e.String()
+		}
+		clauses = append(clauses, "PARTITION BY "+strings.Join(exprs, ", "))
+	}
+	if len(w.orderBy) > 0 {
+		items := make([]string, len(w.orderBy))
+		for i, item := range w.orderBy {
+			items[i] = item.String()
+		}
+		clauses = append(clauses, "ORDER BY "+strings.Join(items, ", "))
+	}
+	if w.frame != nil {
+		clauses = append(clauses, fmt.Sprintf("ROWS BETWEEN %s AND %s", frameBound(w.frame.start), frameBound(w.frame.end)))
+	}
+	fmt.Fprintf(&b, " OVER (%s)", strings.Join(clauses, " "))
+	return b.String()
+}
+
+func frameBound(offset int) string {
+	switch {
+	case offset == -unboundedFrame:
+		return "UNBOUNDED PRECEDING"
+	case offset < 0:
+		return fmt.Sprintf("%d PRECEDING", -offset)
+	case offset == 0:
+		return "CURRENT ROW"
+	case offset == unboundedFrame:
+		return "UNBOUNDED FOLLOWING"
+	}
+	return fmt.Sprintf("%d FOLLOWING", offset)
+}
+
+// computeWindows evaluates the window functions of the select list and
+// ORDER BY for every row of envs.
+func (q *Query) computeWindows(envs []*evalEnv, fieldMap map[string]int) error {
+	exprs := make([]expr, 0, len(q.columns)+len(q.orderBy))
+	for _, item := range q.columns {
+		if !item.star {
+			exprs = append(exprs, item.expr)
+		}
+	}
+	for _, item := range q.orderBy {
+		exprs = append(exprs, q.resolveAliases(item.expr, fieldMap))
+	}
+
+	windows := windowExprs(exprs)
+	if len(windows) == 0 {
+		return nil
+	}
+	for _, w := range windows {
+		if containsWindow(w) {
+			return fmt.Errorf("window functions cannot be nested: %s", w)
+		}
+	}
+
+	for _, env := range envs {
+		env.windows = make(map[*windowExpr]interface{}, len(windows))
+	}
+	for _, w := range windows {
+		if err := w.compute(envs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// windowExprs returns the window functions in exprs, leaving out any nested
+// in another.
+func windowExprs(exprs []expr) []*windowExpr {
+	var windows []*windowExpr
+	for _, e := range exprs {
+		walkExpr(e, func(e expr) bool {
+			w, ok := e.(*windowExpr)
+			if ok && !slices.Contains(windows, w) {
+				windows = append(windows, w)
+			}
+			return !ok
+		})
+	}
+	return windows
+}
+
+// containsWindow reports whether another window function is nested in w.
+func containsWindow(w *windowExpr) bool {
+	found := false
+	walkExpr(w, func(e expr) bool {
+		if _, ok := e.(*windowExpr); ok && e != w {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// compute stores the value of w for every row of envs.
+func (w *windowExpr) compute(envs []*evalEnv) error {
+	partitions, err := partitionBy(envs, w.partitionBy)
+	if err != nil {
+		return err
+	}
+
+	for _, part := range partitions {
+		// Sort a copy: the rows keep their own order in the result.
+		part = slices.Clone(part)
+		keys, err := sortEnvs(part, w.orderBy)
+		if err != nil {
+			return err
+		}
+
+		if w.agg != nil {
+			if err := w.aggregate(part, keys); err != nil {
+				return err
+			}
+			continue
+		}
+
+		rank, denseRank := 0, 0
+		for i, env := range part {
+			if i == 0 || compareKeys(keys[i-1], keys[i], w.orderBy) != 0 {
+				rank, denseRank = i+1, denseRank+1
+			}
+
+			var value interface{}
+			switch w.fn {
+			case "ROW_NUMBER":
+				value = i + 1
+			case "RANK":
+				value = rank
+			case "DENSE_RANK":
+				value = denseRank
+			case "LAG", "LEAD":
+				if value, err = w.offsetValue(part, i); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unknown window function %s", w.fn)
+			}
+			env.windows[w] = value
+		}
+	}
+	return nil
+}
+
+// offsetValue returns the LAG or LEAD of row i of a sorted partition: its
+// first argument evaluated offset rows before or after, or the default when
+// there is no such row.
+func (w *windowExpr) offsetValue(part []*evalEnv, i int) (interface{}, error) {
+	offset := int64(1)
+	if len(w.args) > 1 {
+		value, err := w.args[1].eval(part[i])
+		if err != nil {
+			return nil, err
+		}
+		if offset, err = intArg(value); err != nil || offset < 0 {
+			return nil, fmt.Errorf("%s offset must be a non-negative integer, not %s", w.fn, formatValue(value))
+		}
+	}
+
+	j := int64(i) + offset
+	if w.fn == "LAG" {
+		j = int64(i) - offset
+	}
+	if j < 0 || j >= int64(len(part)) {
+		if len(w.args) > 2 {
+			return w.args[2].eval(part[i])
+		}
+		return nil, nil
+	}
+	return w.args[0].eval(part[j])
+}
+
+// aggregate computes an aggregate window function over the frame of each row
+// of a sorted partition. Without a frame clause the frame runs from the
+// first row to the last row that sorts equal to the current one, which is
+// the whole partition when there is no ORDER BY.
+func (w *windowExpr) aggregate(part []*evalEnv, keys [][]interface{}) error {
+	n := len(part)
+	var peerEnd []int
+	if w.frame == nil {
+		peerEnd = make([]int, n)
+		for i := n - 1; i >= 0; i-- {
+			peerEnd[i] = i + 1
+			if i+1 < n && compareKeys(keys[i], keys[i+1], w.orderBy) == 0 {
+				peerEnd[i] = peerEnd[i+1]
+			}
+		}
+	}
+
+	// A frame that starts at the first row only grows, so a running
+	// accumulator serves every row.
+	var running *accumulator
+	if w.frame == nil || w.frame.start == -unboundedFrame {
+		running = newAccumulator(w.agg)
+	}
+	added := 0
+
+	for i, env := range part {
+		var lo, hi int
+		if w.frame == nil {
+			lo, hi = 0, peerEnd[i]
+		} else {
+			lo = frameRow(i, w.frame.start, n)
+			hi = max(frameRow(i+1, w.frame.end, n), lo)
+		}
+
+		var value interface{}
+		var err error
+		if running != nil {
+			for ; added < hi; added++ {
+				if err := running.add(part[added]); err != nil {
+					return err
+				}
+			}
+			value, err = running.result()
+		} else {
+			value, err = w.agg.compute(part[lo:hi])
+		}
+		if err != nil {
+			return err
+		}
+		env.windows[w] = value
+	}
+	return nil
+}
+
+// frameRow returns the row offset rows from row i, clamped to 0 through n.
+func frameRow(i, offset, n int) int {
+	offset = min(max(offset, -n), n) // keeps unbounded offsets from overflowing
+	return min(max(i+offset, 0), n)
+}
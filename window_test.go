@@ -0,0 +1,110 @@
+package sheetsql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuery_WindowFunctions(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected [][]interface{}
+		wantErr  string
+	}{
+		{
+			name:     "running total per partition",
+			sql:      "SELECT Date, Account, SUM(Amount) OVER (PARTITION BY Account ORDER BY Date) AS Balance FROM Ledger",
+			expected: [][]interface{}{{"2024-01-01", "cash", int64(100)}, {"2024-01-02", "bank", int64(50)}, {"2024-01-02", "cash", int64(70)}, {"2024-01-03", "cash", int64(90)}, {"2024-01-05", "bank", int64(100)}, {"2024-01-06", "cash", int64(90)}},
+		},
+		{
+			name:     "running total includes peers",
+			sql:      "SELECT Date, SUM(Amount) OVER (ORDER BY Date) FROM Ledger",
+			expected: [][]interface{}{{"2024-01-01", int64(100)}, {"2024-01-02", int64(120)}, {"2024-01-02", int64(120)}, {"2024-01-03", int64(140)}, {"2024-01-05", int64(190)}, {"2024-01-06", int64(190)}},
+		},
+		{
+			name:     "moving average",
+			sql:      "SELECT Date, AVG(Amount) OVER (ORDER BY Date ROWS BETWEEN 1 PRECEDING AND CURRENT ROW) AS Moving FROM Ledger WHERE Account = 'cash'",
+			expected: [][]interface{}{{"2024-01-01", 100.0}, {"2024-01-02", 35.0}, {"2024-01-03", -5.0}, {"2024-01-06", 20.0}},
+		},
+		{
+			name:     "frame to the end",
+			sql:      "SELECT Date, COUNT(*) OVER (PARTITION BY Account ORDER BY Date ROWS BETWEEN CURRENT ROW AND UNBOUNDED FOLLOWING) AS Remaining FROM Ledger",
+			expected: [][]interface{}{{"2024-01-01", 4}, {"2024-01-02", 2}, {"2024-01-02", 3}, {"2024-01-03", 2}, {"2024-01-05", 1}, {"2024-01-06", 1}},
+		},
+		{
+			name: "ranking ties",
+			sql: "SELECT Amount, ROW_NUMBER() OVER (ORDER BY Amount DESC) AS N, RANK() OVER (ORDER BY Amount DESC) AS R, " +
+				"DENSE_RANK() OVER (ORDER BY Amount DESC) AS D FROM Ledger WHERE Amount IS NOT NULL ORDER BY N",
+			expected: [][]interface{}{{"100", 1, 1, 1}, {"50", 2, 2, 2}, {"50", 3, 2, 2}, {"20", 4, 4, 3}, {"-30", 5, 5, 4}},
+		},
+		{
+			name: "lag and lead",
+			sql: "SELECT Date, Amount - LAG(Amount) OVER (ORDER BY Date) AS Change, LEAD(Date, 2, 'end') OVER (ORDER BY Date) AS Later " +
+				"FROM Ledger WHERE Account = 'cash'",
+			expected: [][]interface{}{{"2024-01-01", nil, "2024-01-03"}, {"2024-01-02", int64(-130), "2024-01-06"}, {"2024-01-03", int64(50), "end"}, {"2024-01-06", nil, "end"}},
+		},
+		{
+			name:     "over groups",
+			sql:      "SELECT Account, SUM(Amount) AS Total, RANK() OVER (ORDER BY SUM(Amount) DESC) AS Place FROM Ledger GROUP BY Account",
+			expected: [][]interface{}{{"cash", int64(90), 2}, {"bank", int64(100), 1}},
+		},
+		{
+			name:     "in ORDER BY",
+			sql:      "SELECT Date FROM Ledger WHERE Account = 'bank' ORDER BY ROW_NUMBER() OVER (ORDER BY Date DESC)",
+			expected: [][]interface{}{{"2024-01-05"}, {"2024-01-02"}},
+		},
+		{
+			name:    "in WHERE",
+			sql:     "SELECT Date FROM Ledger WHERE ROW_NUMBER() OVER () > 1",
+			wantErr: "window function ROW_NUMBER is not allowed here",
+		},
+		{
+			name:    "nested",
+			sql:     "SELECT SUM(ROW_NUMBER() OVER ()) OVER () FROM Ledger",
+			wantErr: "window functions cannot be nested: SUM(ROW_NUMBER() OVER ()) OVER ()",
+		},
+		{
+			name:    "negative offset",
+			sql:     "SELECT LAG(Date, Amount) OVER () FROM Ledger WHERE Account = 'cash'",
+			wantErr: "LAG offset must be a non-negative integer, not -30",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &countingBackend{MemoryBackend: NewMemoryBackend()}
+			backend.SetSheet("Ledger", [][]interface{}{
+				{"Date", "Account", "Amount"},
+				{"2024-01-01", "cash", "100"},
+				{"2024-01-02", "bank", "50"},
+				{"2024-01-02", "cash", "-30"},
+				{"2024-01-03", "cash", "20"},
+				{"2024-01-05", "bank", "50"},
+				{"2024-01-06", "cash"},
+			})
+
+			query, err := NewSQLParser(NewClientWithBackend("test-id", backend)).parseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("parseSQL() error = %v", err)
+			}
+			if got := query.String(); got != tt.sql {
+				t.Errorf("String() = %q, expected %q", got, tt.sql)
+			}
+
+			_, rows, err := query.rows()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("rows() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rows() error = %v", err)
+			}
+			if !reflect.DeepEqual(rows, tt.expected) {
+				t.Errorf("rows = %v, expected %v", rows, tt.expected)
+			}
+		})
+	}
+}